@@ -0,0 +1,365 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError 绑定结构体时一个字段的错误
+type FieldError struct {
+	Field string // 结构体中的字段路径, 如HDFS.Replication
+	Key   string
+	Value string
+	Err   error
+}
+
+// Error 实现error接口
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s (key %s, value %q): %v", e.Field, e.Key, e.Value, e.Err)
+}
+
+// Unwrap 返回底层错误
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindError 汇总绑定结构体时所有字段的错误
+type BindError struct {
+	Errors []*FieldError
+}
+
+// Error 实现error接口
+func (e *BindError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d field error(s): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fieldTag 解析后的xmlconfig标签
+type fieldTag struct {
+	key    string
+	def    string
+	hasDef bool
+	sep    string
+	unit   time.Duration
+}
+
+// parseFieldTag 解析形如"dfs.replication,sep=;,default=3"的标签, default必须是最后一个选项, 其值可以包含逗号.
+// unit选项指定时间字段中不带后缀的数字的单位, 默认为ms
+func parseFieldTag(tag string) fieldTag {
+	ft := fieldTag{sep: ",", unit: time.Millisecond}
+	parts := strings.SplitN(tag, ",", 2)
+	ft.key = strings.TrimSpace(parts[0])
+	rest := ""
+	if len(parts) == 2 {
+		rest = parts[1]
+	}
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "default="):
+			ft.def, ft.hasDef = rest[len("default="):], true
+			rest = ""
+		case strings.HasPrefix(rest, "sep=") && len(rest) > len("sep="):
+			// 分隔符只取一个字符, 因此可以是逗号
+			ft.sep = rest[len("sep=") : len("sep=")+1]
+			rest = strings.TrimPrefix(rest[len("sep=")+1:], ",")
+		default:
+			opt := rest
+			if i := strings.IndexByte(rest, ','); i >= 0 {
+				opt, rest = rest[:i], rest[i+1:]
+			} else {
+				rest = ""
+			}
+			if strings.HasPrefix(opt, "unit=") {
+				if d, err := parseDuration("1"+opt[len("unit="):], time.Millisecond); err == nil {
+					ft.unit = d
+				}
+			}
+		}
+	}
+	return ft
+}
+
+// joinKey 拼接key前缀
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	if key == "" {
+		return prefix
+	}
+	return prefix + "." + key
+}
+
+// isStruct 判断字段是否为需要递归绑定的结构体或结构体指针
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// Bind 根据xmlconfig标签将配置绑定到v指向的结构体, 如
+//
+//	type HDFS struct {
+//	    Replication int           `xmlconfig:"dfs.replication,default=3"`
+//	    Dirs        []string      `xmlconfig:"dfs.datanode.data.dir"`
+//	    Heartbeat   time.Duration `xmlconfig:"dfs.heartbeat.interval,default=3s"`
+//	    NameNode    NameNode      `xmlconfig:"dfs.namenode"`
+//	}
+//
+// 嵌套结构体字段的标签作为其内部字段key的前缀, 指针字段在key存在或有默认值时才会分配.
+// 切片按sep选项(默认为逗号)分割并去除空白. 所有字段的错误汇总在*BindError中返回
+func (x *XmlConfig) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("bind target must be a non-nil pointer to struct")
+	}
+	values := x.Snapshot()
+	var errs []*FieldError
+	bindStruct(rv.Elem(), "", "", values, &errs)
+	if len(errs) > 0 {
+		return &BindError{Errors: errs}
+	}
+	return nil
+}
+
+// bindStruct 绑定v中的字段, 有任一字段的key存在或使用了默认值时返回true
+func bindStruct(v reflect.Value, prefix, path string, values map[string]string, errs *[]*FieldError) bool {
+	bound := false
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, tagged := f.Tag.Lookup("xmlconfig")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		ft := parseFieldTag(tag)
+		fv := v.Field(i)
+		fieldPath := joinKey(path, f.Name)
+		if isStruct(f.Type) {
+			if f.Type.Kind() == reflect.Ptr && fv.IsNil() {
+				// 先绑定到新分配的值, 没有绑定任何字段时保持nil
+				n := reflect.New(f.Type.Elem())
+				if bindStruct(n.Elem(), joinKey(prefix, ft.key), fieldPath, values, errs) {
+					fv.Set(n)
+					bound = true
+				}
+				continue
+			}
+			if f.Type.Kind() == reflect.Ptr {
+				fv = fv.Elem()
+			}
+			if bindStruct(fv, joinKey(prefix, ft.key), fieldPath, values, errs) {
+				bound = true
+			}
+			continue
+		}
+		if !tagged || ft.key == "" {
+			continue
+		}
+		key := joinKey(prefix, ft.key)
+		s, ok := values[key]
+		if !ok {
+			if !ft.hasDef {
+				continue
+			}
+			s = ft.def
+		}
+		bound = true
+		if err := setField(fv, s, ft); err != nil {
+			*errs = append(*errs, &FieldError{Field: fieldPath, Key: key, Value: s, Err: err})
+		}
+	}
+	return bound
+}
+
+// setField 将字符串s转换为字段类型并赋值
+func setField(fv reflect.Value, s string, ft fieldTag) error {
+	t := fv.Type()
+	if t == durationType {
+		d, err := parseDuration(s, ft.unit)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		n := reflect.New(t.Elem())
+		if err := setField(n.Elem(), s, ft); err != nil {
+			return err
+		}
+		fv.Set(n)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(t, 0, 0)
+		if strings.TrimSpace(s) != "" {
+			for _, part := range strings.Split(s, ft.sep) {
+				e := reflect.New(t.Elem()).Elem()
+				if err := setField(e, strings.TrimSpace(part), ft); err != nil {
+					return err
+				}
+				slice = reflect.Append(slice, e)
+			}
+		}
+		fv.Set(slice)
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(strings.TrimSpace(s), 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), t.Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", t)
+	}
+	return nil
+}
+
+// formatField 将字段值转换为字符串, 指针为nil时第二个返回值为false
+func formatField(fv reflect.Value, sep string) (string, bool, error) {
+	t := fv.Type()
+	if t == durationType {
+		return formatDuration(time.Duration(fv.Int())), true, nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return "", false, nil
+		}
+		return formatField(fv.Elem(), sep)
+	case reflect.Slice:
+		parts := make([]string, 0, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			s, _, err := formatField(fv.Index(i), sep)
+			if err != nil {
+				return "", false, err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, sep), true, nil
+	case reflect.String:
+		return fv.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, t.Bits()), true, nil
+	}
+	return "", false, fmt.Errorf("unsupported field type %s", t)
+}
+
+// SetStruct 按xmlconfig标签将结构体v的字段值写入配置, v可以是结构体或结构体指针.
+// 值为nil的指针字段会被跳过, 无法写入的字段(如final的key)汇总在*BindError中返回
+func (x *XmlConfig) SetStruct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("SetStruct requires a struct or a non-nil pointer to struct")
+	}
+	var entries []overlayEntry
+	var fields []string
+	var errs []*FieldError
+	collectStruct(rv, "", "", &entries, &fields, &errs)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for i, e := range entries {
+		if err := x.setValue(e.key, e.value); err != nil {
+			errs = append(errs, &FieldError{Field: fields[i], Key: e.key, Value: e.value, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return &BindError{Errors: errs}
+	}
+	return nil
+}
+
+func collectStruct(v reflect.Value, prefix, path string, entries *[]overlayEntry, fields *[]string, errs *[]*FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, tagged := f.Tag.Lookup("xmlconfig")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		ft := parseFieldTag(tag)
+		fv := v.Field(i)
+		fieldPath := joinKey(path, f.Name)
+		if isStruct(f.Type) {
+			if f.Type.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			collectStruct(fv, joinKey(prefix, ft.key), fieldPath, entries, fields, errs)
+			continue
+		}
+		if !tagged || ft.key == "" {
+			continue
+		}
+		key := joinKey(prefix, ft.key)
+		s, ok, err := formatField(fv, ft.sep)
+		if err != nil {
+			*errs = append(*errs, &FieldError{Field: fieldPath, Key: key, Err: err})
+		} else if ok {
+			*entries = append(*entries, overlayEntry{key: key, value: s})
+			*fields = append(*fields, fieldPath)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bindNameNode struct {
+	Address  string  `xmlconfig:"rpc-address"`
+	Handlers *int    `xmlconfig:"handler.count,default=10"`
+	Safemode float64 `xmlconfig:"safemode.threshold-pct,default=0.999"`
+}
+
+type bindHDFS struct {
+	Replication uint8         `xmlconfig:"dfs.replication,default=3"`
+	BlockSize   int64         `xmlconfig:"dfs.blocksize"`
+	Permissions bool          `xmlconfig:"dfs.permissions.enabled,default=true"`
+	Dirs        []string      `xmlconfig:"dfs.datanode.data.dir"`
+	Ports       []uint16      `xmlconfig:"dfs.ports,sep=;,default=50010;50020"`
+	Heartbeat   time.Duration `xmlconfig:"dfs.heartbeat.interval,unit=s,default=3"`
+	NameNode    bindNameNode  `xmlconfig:"dfs.namenode"`
+	Backup      *bindNameNode `xmlconfig:"dfs.backup"`
+	Missing     *string       `xmlconfig:"not.exist"`
+	Ignored     string        `xmlconfig:"-"`
+	Untagged    string
+	unexported  string `xmlconfig:"dfs.replication"`
+}
+
+func TestParseFieldTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want fieldTag
+	}{
+		{"a.b", fieldTag{key: "a.b", sep: ",", unit: time.Millisecond}},
+		{"a.b,default=1,2", fieldTag{key: "a.b", def: "1,2", hasDef: true, sep: ",", unit: time.Millisecond}},
+		{"a.b,sep=;,default=1;2", fieldTag{key: "a.b", def: "1;2", hasDef: true, sep: ";", unit: time.Millisecond}},
+		{"a.b,omitempty,sep=,", fieldTag{key: "a.b", sep: ",", unit: time.Millisecond}},
+		{"", fieldTag{sep: ",", unit: time.Millisecond}},
+		{"a.b,unit=s,default=30", fieldTag{key: "a.b", def: "30", hasDef: true, sep: ",", unit: time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseFieldTag(tt.tag))
+		})
+	}
+}
+
+func TestXmlConfig_Bind(t *testing.T) {
+	x := &XmlConfig{
+		configurations: newExpandCase(
+			"dfs.blocksize", "134217728",
+			"dfs.datanode.data.dir", " /data/1 , /data/2",
+			"dfs.heartbeat.interval", "5s",
+			"dfs.namenode.rpc-address", "nn:8020",
+			"dfs.backup.handler.count", "20",
+		),
+	}
+	var cfg bindHDFS
+	assert.Nil(t, x.Bind(&cfg))
+	handlers, backupHandlers := 10, 20
+	assert.Equal(t, bindHDFS{
+		Replication: 3,
+		BlockSize:   134217728,
+		Permissions: true,
+		Dirs:        []string{"/data/1", "/data/2"},
+		Ports:       []uint16{50010, 50020},
+		Heartbeat:   5 * time.Second,
+		NameNode:    bindNameNode{Address: "nn:8020", Handlers: &handlers, Safemode: 0.999},
+		Backup:      &bindNameNode{Handlers: &backupHandlers, Safemode: 0.999},
+	}, cfg)
+}
+
+func TestXmlConfig_Bind_NilStructPointer(t *testing.T) {
+	type tls struct {
+		Cert string `xmlconfig:"cert"`
+		Key  string `xmlconfig:"key"`
+	}
+	type server struct {
+		TLS    *tls `xmlconfig:"server.tls"`
+		Client *tls `xmlconfig:"client.tls"`
+	}
+	x := &XmlConfig{
+		configurations: newExpandCase("server.tls.cert", "server.pem"),
+	}
+	var cfg server
+	assert.Nil(t, x.Bind(&cfg))
+	// 没有任何key时不分配
+	assert.Equal(t, server{TLS: &tls{Cert: "server.pem"}}, cfg)
+}
+
+func TestXmlConfig_Bind_Error(t *testing.T) {
+	x := &XmlConfig{
+		configurations: newExpandCase(
+			"dfs.replication", "1024",
+			"dfs.blocksize", "128m",
+			"dfs.ports", "1;x",
+		),
+	}
+	var cfg bindHDFS
+	err := x.Bind(&cfg)
+	var bindErr *BindError
+	assert.True(t, errors.As(err, &bindErr))
+	assert.Equal(t, 3, len(bindErr.Errors))
+	assert.Equal(t, "Replication", bindErr.Errors[0].Field)
+	assert.Equal(t, "dfs.blocksize", bindErr.Errors[1].Key)
+	assert.Equal(t, "1;x", bindErr.Errors[2].Value)
+	assert.True(t, errors.Is(bindErr.Errors[0], strconv.ErrRange))
+	assert.NotNil(t, x.Bind(cfg))
+}
+
+func TestXmlConfig_SetStruct(t *testing.T) {
+	handlers := 8
+	cfg := bindHDFS{
+		Replication: 2,
+		Dirs:        []string{"/data/1", "/data/2"},
+		Ports:       []uint16{1, 2},
+		Heartbeat:   3 * time.Second,
+		NameNode:    bindNameNode{Address: "nn:8020", Handlers: &handlers, Safemode: 0.5},
+	}
+	x := NewXmlConfig()
+	assert.Nil(t, x.SetStruct(&cfg))
+	assert.Equal(t, map[string]string{
+		"dfs.replication":                     "2",
+		"dfs.blocksize":                       "0",
+		"dfs.permissions.enabled":             "false",
+		"dfs.datanode.data.dir":               "/data/1,/data/2",
+		"dfs.ports":                           "1;2",
+		"dfs.heartbeat.interval":              "3s",
+		"dfs.namenode.rpc-address":            "nn:8020",
+		"dfs.namenode.handler.count":          "8",
+		"dfs.namenode.safemode.threshold-pct": "0.5",
+	}, x.Snapshot())
+
+	var back bindHDFS
+	assert.Nil(t, x.Bind(&back))
+	assert.Equal(t, cfg.Dirs, back.Dirs)
+	assert.Equal(t, cfg.NameNode, back.NameNode)
+
+	assert.Nil(t, x.SetFinal("dfs.replication", true))
+	var bindErr *BindError
+	assert.True(t, errors.As(x.SetStruct(cfg), &bindErr))
+	assert.True(t, errors.Is(bindErr.Errors[0], ErrFinalProperty))
+}
@@ -22,11 +22,12 @@
 package xmlconfig
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
+	"sync"
 )
 
 // configuration TODO
@@ -42,6 +43,7 @@ type property struct {
 	Value       string   `xml:"value"`
 	Tag         string   `xml:"tag"`
 	Description string   `xml:"description"`
+	Final       bool     `xml:"final,omitempty"`
 }
 
 // String TODO
@@ -51,18 +53,41 @@ func (p *property) String() string {
 		"    <value>%s</value>\n" +
 		"    <tag>%s</tag>\n" +
 		"    <description>%s</description>\n" +
+		"%s" +
 		"</property>\n"
-	return fmt.Sprintf(f, p.Name, p.Value, p.Tag, p.Description)
+	final := ""
+	if p.Final {
+		final = "    <final>true</final>\n"
+	}
+	return fmt.Sprintf(f, p.Name, p.Value, p.Tag, p.Description, final)
 }
 
 // Equal TODO
 func (p *property) Equal(o *property) bool {
-	return p.Name == o.Name && p.Value == o.Value && p.Tag == o.Tag
+	return p.Name == o.Name && p.Value == o.Value && p.Tag == o.Tag && p.Final == o.Final
 }
 
 // XmlConfig TODO
 type XmlConfig struct {
-	configurations map[string]*property
+	mu              sync.RWMutex
+	configurations  map[string]*property
+	maxExpandDepth  int
+	maxReadSize     int64
+	finalSkipped    []string
+	resources       []resource
+	resourceGen     uint64 // 资源栈每次变化时加一
+	sources         map[string][]string
+	overlay         []overlayEntry
+	keys            []string
+	sortedOutput    bool
+	preserveFormat  bool
+	strict          bool
+	schema          *Schema
+	doc             *document
+	deprecatedInUse map[string]bool
+	subMu           sync.Mutex
+	subscribers     []subscriber
+	nextSubID       int
 }
 
 // NewXmlConfig TODO
@@ -74,42 +99,147 @@ func NewXmlConfig() *XmlConfig {
 
 // String TODO
 func (x *XmlConfig) String() string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
 	str := ""
-	for _, property := range x.configurations {
-		str += property.String()
+	for _, key := range x.orderedKeys() {
+		str += x.configurations[key].String()
 	}
 	return str
 }
 
-// ParseXmlData TODO
+// Snapshot 返回同一时刻所有配置展开变量后的值, 不会读到并发写入的中间状态
+func (x *XmlConfig) Snapshot() map[string]string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.snapshot()
+}
+
+func (x *XmlConfig) snapshot() map[string]string {
+	snapshot := make(map[string]string, len(x.configurations))
+	for key := range x.configurations {
+		snapshot[key], _, _ = x.expandKey(key)
+	}
+	return snapshot
+}
+
+// Clone 返回配置的深拷贝, 拷贝与原配置之后的修改互不影响
+func (x *XmlConfig) Clone() *XmlConfig {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	c := &XmlConfig{
+		configurations: make(map[string]*property, len(x.configurations)),
+		maxExpandDepth: x.maxExpandDepth,
+		maxReadSize:    x.maxReadSize,
+		finalSkipped:   append([]string(nil), x.finalSkipped...),
+		resources:      append([]resource(nil), x.resources...),
+		sources:        make(map[string][]string, len(x.sources)),
+		overlay:        append([]overlayEntry(nil), x.overlay...),
+		keys:           append([]string(nil), x.keys...),
+		sortedOutput:   x.sortedOutput,
+		preserveFormat: x.preserveFormat,
+		strict:         x.strict,
+		schema:         x.schema,
+		doc:            x.doc,
+	}
+	for key := range x.deprecatedInUse {
+		c.addDeprecatedInUse(key)
+	}
+	for key, p := range x.configurations {
+		cp := *p
+		c.configurations[key] = &cp
+	}
+	for key, s := range x.sources {
+		c.sources[key] = append([]string(nil), s...)
+	}
+	return c
+}
+
+// ParseXmlData 解析xml配置, 已被标记为final的key不会被覆盖, 可通过SkippedFinalKeys查看被跳过的key.
+// 数据不会加入资源栈, 解析得到的配置与Set设置的值一样在Reload之后再次应用
 func (x *XmlConfig) ParseXmlData(data []byte) error {
-	c := &configuration{
-		XMLName:    xml.Name{},
-		Properties: make([]property, 0),
+	return x.readXml(bytes.NewReader(data), 0)
+}
+
+// parsedXml 解析得到的xml配置及保留格式的文档
+type parsedXml struct {
+	source string
+	props  []property
+	doc    *document
+}
+
+// parse 解析xml配置, 出错信息中的资源名称为source
+func (x *XmlConfig) parse(data []byte, source string) (parsedXml, error) {
+	props, err := decodeXml(bytes.NewReader(data), source, x.strict)
+	if err != nil {
+		return parsedXml{}, err
 	}
-	if err := xml.Unmarshal(data, c); err != nil {
-		return err
+	p := parsedXml{source: source, props: props}
+	if x.preserveFormat {
+		if p.doc, err = parseDocument(data); err != nil {
+			return parsedXml{}, err
+		}
+	}
+	return p, nil
+}
+
+// putProperties 加入配置项, 弃用的key会被转换为新key
+func (x *XmlConfig) putProperties(p property, source string) {
+	newKeys := handleDeprecation(p.Name)
+	if newKeys[0] != p.Name {
+		x.addDeprecatedInUse(p.Name)
+	}
+	for _, key := range newKeys {
+		p.Name = key
+		x.putProperty(p, source)
 	}
-	for _, p := range c.Properties {
-		x.configurations[p.Name] = &property{
-			XMLName: xml.Name{
-				Space: p.XMLName.Space,
-				Local: p.XMLName.Local,
-			},
-			Name:        p.Name,
-			Value:       p.Value,
-			Tag:         p.Tag,
-			Description: p.Description,
+}
+
+// putProperty 加入解析得到的配置项, 已被标记为final的key不会被覆盖
+func (x *XmlConfig) putProperty(p property, source string) {
+	if old, ok := x.configurations[p.Name]; ok && old.Final {
+		if old.Value != p.Value {
+			x.finalSkipped = append(x.finalSkipped, p.Name)
 		}
+		return
+	} else if !ok {
+		x.addKey(p.Name)
 	}
-	return nil
+	x.configurations[p.Name] = &property{
+		XMLName: xml.Name{
+			Space: p.XMLName.Space,
+			Local: p.XMLName.Local,
+		},
+		Name:        p.Name,
+		Value:       p.Value,
+		Tag:         p.Tag,
+		Description: p.Description,
+		Final:       p.Final,
+	}
+	x.addSource(p.Name, source)
 }
 
-// BuildXmlData 构建xml配置
+// SkippedFinalKeys 返回最近一次解析时因final而未被覆盖的key
+func (x *XmlConfig) SkippedFinalKeys() []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return append([]string(nil), x.finalSkipped...)
+}
+
+// BuildXmlData 构建xml配置, 编辑模式下在原始文档上应用修改
 func (x *XmlConfig) BuildXmlData() ([]byte, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.doc != nil {
+		return x.buildDocument()
+	}
+	return x.buildXmlData()
+}
+
+func (x *XmlConfig) buildXmlData() ([]byte, error) {
 	var properties []property
-	for _, property := range x.configurations {
-		properties = append(properties, *property)
+	for _, key := range x.orderedKeys() {
+		properties = append(properties, *x.configurations[key])
 	}
 	c := &configuration{
 		Properties: properties,
@@ -123,26 +253,58 @@ func (x *XmlConfig) BuildXmlData() ([]byte, error) {
 	return data, nil
 }
 
-// ReadXmlFile 从xml文件中读取配置
+// ReadXmlFile 从xml文件中读取配置, 文件会被加入资源栈, 等同于AddResource
 func (x *XmlConfig) ReadXmlFile(xmlFilePath string) error {
-	xmlFile, err := os.Open(xmlFilePath)
-	if err != nil {
-		return err
+	return x.AddResource(xmlFilePath)
+}
+
+// Read 从IO中读取配置, 会读取r直到结束, data参数已不再使用, 仅为兼容保留
+//
+// Deprecated: 使用ReadXml
+func (x *XmlConfig) Read(data []byte, r io.Reader) error {
+	return x.ReadXml(r)
+}
+
+// ReadXml 从r中流式读取xml配置直到结束, 不需要预先分配缓冲区. 与ParseXmlData一样不会加入资源栈.
+// 输入超过SetMaxReadSize设置的大小时返回ErrInputTooLarge, 配置保持不变
+func (x *XmlConfig) ReadXml(r io.Reader) error {
+	x.mu.RLock()
+	maxSize := x.maxReadSize
+	x.mu.RUnlock()
+	return x.readXml(r, maxSize)
+}
+
+// readXml 读取xml配置并应用, maxSize<=0时不限制大小
+func (x *XmlConfig) readXml(r io.Reader, maxSize int64) error {
+	x.mu.RLock()
+	preserve, strict := x.preserveFormat, x.strict
+	x.mu.RUnlock()
+	if maxSize > 0 {
+		r = &limitedReader{r: r, n: maxSize}
+	}
+	var buf bytes.Buffer
+	if preserve {
+		r = io.TeeReader(r, &buf)
 	}
-	defer xmlFile.Close()
-	data, err := ioutil.ReadAll(xmlFile)
+	props, err := decodeXml(r, "", strict)
 	if err != nil {
 		return err
 	}
-	return x.ParseXmlData(data)
+	var doc *document
+	if preserve {
+		if doc, err = parseDocument(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	x.applyOnce(toProperties(props), doc)
+	return nil
 }
 
-// Read 从IO中读取配置
-func (x *XmlConfig) Read(data []byte, r io.Reader) error {
-	if _, err := r.Read(data); err != nil {
-		return err
-	}
-	return x.ParseXmlData(data)
+// SetMaxReadSize 设置ReadXml最多读取的字节数, size<=0时不限制
+func (x *XmlConfig) SetMaxReadSize(size int64) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.maxReadSize = size
 }
 
 // WriteXmlFile 将配置信息写入xml文件
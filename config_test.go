@@ -28,8 +28,11 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -144,6 +147,72 @@ func TestXmlConfig_Read(t *testing.T) {
 	}
 }
 
+func TestXmlConfig_ReadXml(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       io.Reader
+		maxSize int64
+		wantErr error
+	}{
+		{name: "逐字节读取", r: iotest.OneByteReader(strings.NewReader(stringCase))},
+		{name: "每次读取一半", r: iotest.HalfReader(strings.NewReader(stringCase))},
+		{name: "数据与EOF同时返回", r: iotest.DataErrReader(strings.NewReader(stringCase))},
+		{name: "忽略未知元素", r: strings.NewReader(strings.Replace(stringCase, "<property>", "<include><property/></include><property>", 1))},
+		{name: "未超过大小限制", r: strings.NewReader(stringCase), maxSize: int64(len(stringCase))},
+		{name: "超过大小限制", r: strings.NewReader(stringCase), maxSize: 64, wantErr: ErrInputTooLarge},
+		{name: "读取失败", r: iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader(stringCase))), wantErr: iotest.ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewXmlConfig()
+			x.SetMaxReadSize(tt.maxSize)
+			err := x.ReadXml(tt.r)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, x.configurations)
+				assert.Empty(t, x.GetResources())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, newConfigurations(), x.configurations)
+			assert.Nil(t, x.Reload())
+			assert.Equal(t, newConfigurations(), x.configurations)
+		})
+	}
+
+	x := NewXmlConfig()
+	assert.NotNil(t, x.ReadXml(strings.NewReader("<properties></properties>")))
+	assert.NotNil(t, x.ReadXml(strings.NewReader("<configuration><property>")))
+	assert.NotNil(t, x.ReadXml(strings.NewReader("")))
+}
+
+func TestXmlConfig_ReadXml_Large(t *testing.T) {
+	const n = 10000
+	r, w := io.Pipe()
+	go func() {
+		w.Write([]byte("<configuration>\n"))
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(w, "<property><name>key%d</name><value>%d</value></property>\n", i, i)
+		}
+		w.Write([]byte("</configuration>\n"))
+		w.Close()
+	}()
+	x := NewXmlConfig()
+	assert.Nil(t, x.ReadXml(r))
+	assert.Len(t, x.GetConfigKeys(), n)
+	assert.Equal(t, strconv.Itoa(n-1), x.GetString(fmt.Sprintf("key%d", n-1), ""))
+}
+
+func TestXmlConfig_ReadXml_PreserveFormat(t *testing.T) {
+	x := NewXmlConfig()
+	x.SetPreserveFormat(true)
+	assert.Nil(t, x.ReadXml(iotest.OneByteReader(strings.NewReader(stringCase))))
+	x.SetString("name1", "changed")
+	data, err := x.BuildXmlData()
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Replace(stringCase, "value1", "changed", 1), string(data))
+}
+
 func TestXmlConfig_Write(t *testing.T) {
 	type fields struct {
 		configurations map[string]*property
@@ -268,3 +337,180 @@ func Test_property_Equal(t *testing.T) {
 		})
 	}
 }
+
+var finalCase = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" +
+	"<configuration>\n" +
+	"    <property>\n" +
+	"        <name>name1</name>\n" +
+	"        <value>value1</value>\n" +
+	"        <tag></tag>\n" +
+	"        <description></description>\n" +
+	"        <final>true</final>\n" +
+	"    </property>\n" +
+	"</configuration>"
+
+func TestXmlConfig_ParseXmlData_Final(t *testing.T) {
+	tests := []struct {
+		name        string
+		site        string
+		wantValue   string
+		wantSkipped []string
+	}{
+		{
+			name:        "final不被覆盖",
+			site:        "<configuration><property><name>name1</name><value>other</value></property></configuration>",
+			wantValue:   "value1",
+			wantSkipped: []string{"name1"},
+		},
+		{
+			name:        "相同值不记录",
+			site:        "<configuration><property><name>name1</name><value>value1</value></property></configuration>",
+			wantValue:   "value1",
+			wantSkipped: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewXmlConfig()
+			assert.Nil(t, x.ParseXmlData([]byte(finalCase)))
+			assert.True(t, x.IsFinal("name1"))
+			assert.Nil(t, x.ParseXmlData([]byte(tt.site)))
+			assert.Equal(t, tt.wantValue, x.GetString("name1", ""))
+			assert.Equal(t, tt.wantSkipped, append([]string{}, x.SkippedFinalKeys()...))
+			assert.True(t, x.IsFinal("name1"))
+		})
+	}
+}
+
+func TestXmlConfig_ParseXmlData_NotRetained(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-default.xml", []byte(coreDefault)))
+	for _, value := range []string{"1", "2", "3", "4"} {
+		assert.Nil(t, x.ParseXmlData([]byte(`<configuration><property><name>dfs.replication</name><value>`+value+`</value><description>replication</description></property></configuration>`)))
+	}
+	assert.Equal(t, []string{"core-default.xml"}, x.GetResources())
+
+	// 重新加载或加入低优先级的资源后解析得到的配置仍然有效
+	assert.Nil(t, x.Reload())
+	assert.Nil(t, x.AddSource(BytesSource("low.xml", []byte(`<configuration><property><name>dfs.replication</name><value>9</value></property></configuration>`)), -1))
+	assert.Equal(t, []string{"low.xml", "core-default.xml"}, x.GetResources())
+	p, ok := x.Lookup("dfs.replication")
+	assert.True(t, ok)
+	assert.Equal(t, "4", p.Value)
+	assert.Equal(t, "replication", p.Description)
+	assert.Equal(t, "file:///", x.GetString("fs.defaultFS", ""))
+}
+
+func TestXmlConfig_BuildXmlData_Final(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.ParseXmlData([]byte(finalCase)))
+	data, err := x.BuildXmlData()
+	assert.Nil(t, err)
+	assert.Equal(t, finalCase, string(data))
+}
+
+func TestXmlConfig_Concurrent(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.ParseXmlData([]byte(stringCase)))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				x.SetString("name1", strconv.Itoa(j))
+				x.SetInt("key"+strconv.Itoa(i), int64(j))
+				x.SetIfUnset("unset"+strconv.Itoa(j), "v")
+				x.unset("unset" + strconv.Itoa(j))
+				x.SetString("ref", "${name1}")
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				x.GetString("name1", "")
+				_, _ = x.GetInt("key0", 0)
+				x.GetBool("name1", false)
+				x.GetStrings("ref", ",")
+				x.GetPropsWithPrefix("key")
+				x.GetConfigKeys()
+				x.GetPropertySources("name1")
+				_, _ = x.BuildXmlData()
+				_ = x.String()
+			}
+		}()
+	}
+	wg.Wait()
+	_, err := x.GetInt("name1", 0)
+	assert.Nil(t, err)
+}
+
+func TestXmlConfig_Snapshot(t *testing.T) {
+	x := NewXmlConfig()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			data := fmt.Sprintf("<configuration><property><name>a</name><value>%d</value></property>"+
+				"<property><name>b</name><value>${a}</value></property></configuration>", i)
+			assert.Nil(t, x.ParseXmlData([]byte(data)))
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			assert.Equal(t, map[string]string{"a": "199", "b": "199"}, x.Snapshot())
+			return
+		default:
+			snapshot := x.Snapshot()
+			assert.Equal(t, snapshot["a"], snapshot["b"])
+		}
+	}
+}
+
+func TestXmlConfig_Clone(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("test.xml", []byte(stringCase)))
+	c := x.Clone()
+	c.SetString("name1", "changed")
+	c.SetString("name2", "added")
+	assert.Equal(t, "value1", x.GetString("name1", ""))
+	assert.Equal(t, []string{"name1"}, x.GetConfigKeys())
+	assert.Equal(t, []string{"test.xml"}, x.GetPropertySources("name1"))
+	assert.Equal(t, []string{"test.xml", SourceProgrammatic}, c.GetPropertySources("name1"))
+	assert.Nil(t, c.Reload())
+	assert.Equal(t, "changed", c.GetString("name1", ""))
+}
+
+func BenchmarkXmlConfig_GetString(b *testing.B) {
+	x := NewXmlConfig()
+	_ = x.ParseXmlData([]byte(stringCase))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			x.GetString("name1", "")
+		}
+	})
+}
+
+func BenchmarkXmlConfig_GetStringWithWriter(b *testing.B) {
+	x := NewXmlConfig()
+	_ = x.ParseXmlData([]byte(stringCase))
+	stop := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				x.SetInt("writer", int64(i))
+			}
+		}
+	}()
+	defer close(stop)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			x.GetString("name1", "")
+		}
+	})
+}
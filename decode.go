@@ -0,0 +1,234 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ErrInputTooLarge 输入超过了SetMaxReadSize设置的大小
+var ErrInputTooLarge = errors.New("xml input too large")
+
+// position xml中的位置, 行号和列号均从1开始, 列号按字节计算
+type position struct {
+	line   int
+	column int
+}
+
+// posReader 记录已读取内容中每一行的起始偏移, 用于将xml.Decoder的偏移换算为行号和列号
+type posReader struct {
+	r          io.ByteReader
+	off        int64
+	lineStarts []int64
+}
+
+func newPosReader(r io.Reader) *posReader {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &posReader{r: br, lineStarts: []int64{0}}
+}
+
+// ReadByte 实现io.ByteReader接口, xml.Decoder会直接使用该方法读取
+func (p *posReader) ReadByte() (byte, error) {
+	b, err := p.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	p.off++
+	if b == '\n' {
+		p.lineStarts = append(p.lineStarts, p.off)
+	}
+	return b, nil
+}
+
+// Read 实现io.Reader接口
+func (p *posReader) Read(buf []byte) (int, error) {
+	for i := range buf {
+		b, err := p.ReadByte()
+		if err != nil {
+			return i, err
+		}
+		buf[i] = b
+	}
+	return len(buf), nil
+}
+
+// position 返回偏移off所在的位置
+func (p *posReader) position(off int64) position {
+	i := sort.Search(len(p.lineStarts), func(i int) bool { return p.lineStarts[i] > off }) - 1
+	return position{line: i + 1, column: int(off-p.lineStarts[i]) + 1}
+}
+
+// xmlDecoder 流式解码<configuration>下的<property>, 并记录每个配置项的位置
+type xmlDecoder struct {
+	source string
+	pr     *posReader
+	d      *xml.Decoder
+	// positions 与解码得到的配置项一一对应
+	positions []position
+	// strict 为true时检查文档结构, 发现的问题记录在problems中
+	strict   bool
+	problems []*ParseError
+	seen     map[string]position
+}
+
+func newXmlDecoder(r io.Reader, source string, strict bool) *xmlDecoder {
+	pr := newPosReader(r)
+	return &xmlDecoder{source: source, pr: pr, d: xml.NewDecoder(pr), strict: strict}
+}
+
+// decodeXml 使用xml.Decoder从r中流式解码<configuration>下的所有<property>, source为出错时报告的资源名称.
+// strict为true时文档结构有问题返回*ValidationError
+func decodeXml(r io.Reader, source string, strict bool) ([]property, error) {
+	return newXmlDecoder(r, source, strict).decode()
+}
+
+// errorAt 返回出错位置在偏移off处的ParseError
+func (dec *xmlDecoder) errorAt(off int64, name string, err error) *ParseError {
+	pos := dec.pr.position(off)
+	return &ParseError{Source: dec.source, Line: pos.line, Column: pos.column, Property: name, Err: err}
+}
+
+// fail 返回出错位置在当前读取位置的ParseError
+func (dec *xmlDecoder) fail(name string, err error) *ParseError {
+	return dec.errorAt(dec.d.InputOffset(), name, err)
+}
+
+func (dec *xmlDecoder) decode() ([]property, error) {
+	props, err := dec.decodeConfiguration()
+	if !dec.strict {
+		return props, err
+	}
+	if err != nil {
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			pe = dec.fail("", err)
+		}
+		dec.problems = append(dec.problems, pe)
+	}
+	if len(dec.problems) > 0 {
+		return nil, &ValidationError{Problems: dec.problems}
+	}
+	return props, nil
+}
+
+func (dec *xmlDecoder) decodeConfiguration() ([]property, error) {
+	var root *xml.StartElement
+	var rootOff int64
+	for root == nil {
+		rootOff = dec.d.InputOffset()
+		tok, err := dec.d.Token()
+		if err != nil {
+			return nil, dec.fail("", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			root = &se
+		}
+	}
+	if root.Name.Local != "configuration" {
+		err := dec.errorAt(rootOff, "", fmt.Errorf("%w: expected <configuration> but have <%s>", ErrInvalidRoot, root.Name.Local))
+		if !dec.strict {
+			return nil, err
+		}
+		dec.problems = append(dec.problems, err)
+	}
+	props := make([]property, 0)
+	for {
+		off := dec.d.InputOffset()
+		tok, err := dec.d.Token()
+		if err == io.EOF {
+			return nil, dec.fail("", io.ErrUnexpectedEOF)
+		}
+		if err != nil {
+			return nil, dec.fail("", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "property" {
+				if dec.strict {
+					dec.problems = append(dec.problems, dec.errorAt(off, "", fmt.Errorf("%w <%s> in <configuration>", ErrUnknownElement, t.Name.Local)))
+				}
+				if err := dec.d.Skip(); err != nil {
+					return nil, dec.fail("", err)
+				}
+				continue
+			}
+			var p property
+			if dec.strict {
+				err = dec.decodeStrict(&p, &t, off)
+			} else {
+				err = dec.d.DecodeElement(&p, &t)
+			}
+			if err != nil {
+				return nil, dec.fail(p.Name, err)
+			}
+			props = append(props, p)
+			dec.positions = append(dec.positions, dec.pr.position(off))
+		case xml.EndElement:
+			return props, nil
+		}
+	}
+}
+
+// toProperties 将解析得到的配置项转换为Property, Value与RawValue均为原始值
+func toProperties(props []property) []Property {
+	ps := make([]Property, 0, len(props))
+	for _, p := range props {
+		ps = append(ps, Property{
+			Name:        p.Name,
+			Value:       p.Value,
+			RawValue:    p.Value,
+			Tags:        splitTags(p.Tag),
+			Description: p.Description,
+			Final:       p.Final,
+		})
+	}
+	return ps
+}
+
+// limitedReader 读取超过n字节时返回ErrInputTooLarge
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrInputTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrInputTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,214 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Logger 输出弃用警告的日志接口, *log.Logger实现了该接口
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// DeprecationDelta 一个弃用的key及替代它的新key
+type DeprecationDelta struct {
+	Key     string
+	NewKeys []string
+	Message string // 自定义警告信息, 为空时使用默认信息
+}
+
+// deprecatedKeyInfo 弃用key的信息
+type deprecatedKeyInfo struct {
+	newKeys []string
+	message string
+	warned  int32
+}
+
+// warningMessage 返回弃用警告信息
+func (d *deprecatedKeyInfo) warningMessage(key string) string {
+	if d.message != "" {
+		return d.message
+	}
+	return fmt.Sprintf("%s is deprecated. Instead, use %s", key, strings.Join(d.newKeys, ", "))
+}
+
+var (
+	deprecationMu sync.RWMutex
+	deprecations  = make(map[string]*deprecatedKeyInfo)
+	// reverseDeprecations 新key到弃用key的映射
+	reverseDeprecations = make(map[string][]string)
+	deprecationLogger   Logger
+)
+
+// HadoopDeprecations 常用的hadoop弃用key, 默认不注册, 需要时通过AddDeprecations(HadoopDeprecations)注册
+var HadoopDeprecations = []DeprecationDelta{
+	{Key: "fs.default.name", NewKeys: []string{"fs.defaultFS"}},
+	{Key: "fs.checkpoint.dir", NewKeys: []string{"dfs.namenode.checkpoint.dir"}},
+	{Key: "fs.checkpoint.edits.dir", NewKeys: []string{"dfs.namenode.checkpoint.edits.dir"}},
+	{Key: "fs.checkpoint.period", NewKeys: []string{"dfs.namenode.checkpoint.period"}},
+	{Key: "dfs.block.size", NewKeys: []string{"dfs.blocksize"}},
+	{Key: "dfs.name.dir", NewKeys: []string{"dfs.namenode.name.dir"}},
+	{Key: "dfs.name.edits.dir", NewKeys: []string{"dfs.namenode.edits.dir"}},
+	{Key: "dfs.data.dir", NewKeys: []string{"dfs.datanode.data.dir"}},
+	{Key: "dfs.replication.min", NewKeys: []string{"dfs.namenode.replication.min"}},
+	{Key: "dfs.max.objects", NewKeys: []string{"dfs.namenode.max.objects"}},
+	{Key: "dfs.http.address", NewKeys: []string{"dfs.namenode.http-address"}},
+	{Key: "dfs.https.address", NewKeys: []string{"dfs.namenode.https-address"}},
+	{Key: "dfs.secondary.http.address", NewKeys: []string{"dfs.namenode.secondary.http-address"}},
+	{Key: "dfs.backup.address", NewKeys: []string{"dfs.namenode.backup.address"}},
+	{Key: "dfs.permissions", NewKeys: []string{"dfs.permissions.enabled"}},
+	{Key: "dfs.permissions.supergroup", NewKeys: []string{"dfs.permissions.superusergroup"}},
+	{Key: "dfs.safemode.threshold.pct", NewKeys: []string{"dfs.namenode.safemode.threshold-pct"}},
+	{Key: "dfs.safemode.extension", NewKeys: []string{"dfs.namenode.safemode.extension"}},
+	{Key: "dfs.access.time.precision", NewKeys: []string{"dfs.namenode.accesstime.precision"}},
+	{Key: "dfs.datanode.max.xcievers", NewKeys: []string{"dfs.datanode.max.transfer.threads"}},
+	{Key: "dfs.balance.bandwidthPerSec", NewKeys: []string{"dfs.datanode.balance.bandwidthPerSec"}},
+	{Key: "dfs.umask", NewKeys: []string{"fs.permissions.umask-mode"}},
+	{Key: "heartbeat.recheck.interval", NewKeys: []string{"dfs.namenode.heartbeat.recheck-interval"}},
+	{Key: "io.bytes.per.checksum", NewKeys: []string{"dfs.bytes-per-checksum"}},
+	{Key: "hadoop.native.lib", NewKeys: []string{"io.native.lib.available"}},
+	{Key: "topology.script.file.name", NewKeys: []string{"net.topology.script.file.name"}},
+	{Key: "mapred.job.tracker", NewKeys: []string{"mapreduce.jobtracker.address"}},
+	{Key: "mapred.job.name", NewKeys: []string{"mapreduce.job.name"}},
+	{Key: "mapred.map.tasks", NewKeys: []string{"mapreduce.job.maps"}},
+	{Key: "mapred.reduce.tasks", NewKeys: []string{"mapreduce.job.reduces"}},
+	{Key: "mapred.output.compress", NewKeys: []string{"mapreduce.output.fileoutputformat.compress"}},
+}
+
+// AddDeprecation 注册弃用的key, 读写key时会转换为newKeys
+func AddDeprecation(key string, newKeys ...string) {
+	AddDeprecations([]DeprecationDelta{{Key: key, NewKeys: newKeys}})
+}
+
+// AddDeprecations 批量注册弃用的key, 已注册的key会被覆盖
+func AddDeprecations(deltas []DeprecationDelta) {
+	deprecationMu.Lock()
+	defer deprecationMu.Unlock()
+	for _, d := range deltas {
+		if d.Key == "" || len(d.NewKeys) == 0 {
+			continue
+		}
+		if old, ok := deprecations[d.Key]; ok {
+			for _, k := range old.newKeys {
+				reverseDeprecations[k] = removeString(reverseDeprecations[k], d.Key)
+			}
+		}
+		deprecations[d.Key] = &deprecatedKeyInfo{
+			newKeys: append([]string(nil), d.NewKeys...),
+			message: d.Message,
+		}
+		for _, k := range d.NewKeys {
+			reverseDeprecations[k] = append(reverseDeprecations[k], d.Key)
+		}
+	}
+}
+
+// IsDeprecated 判断key是否已被弃用
+func IsDeprecated(key string) bool {
+	deprecationMu.RLock()
+	defer deprecationMu.RUnlock()
+	_, ok := deprecations[key]
+	return ok
+}
+
+// GetDeprecatedKeys 返回被newKey替代的弃用key
+func GetDeprecatedKeys(newKey string) []string {
+	deprecationMu.RLock()
+	defer deprecationMu.RUnlock()
+	keys := append([]string(nil), reverseDeprecations[newKey]...)
+	sort.Strings(keys)
+	return keys
+}
+
+// SetDeprecationLogger 设置输出弃用警告的日志, 为nil时不输出警告. 默认不输出, 可传入log.Default()输出到标准错误
+func SetDeprecationLogger(l Logger) {
+	deprecationMu.Lock()
+	defer deprecationMu.Unlock()
+	deprecationLogger = l
+}
+
+// handleDeprecation 返回key对应的新key, key未被弃用时返回key本身. 每个弃用key只警告一次
+func handleDeprecation(key string) []string {
+	deprecationMu.RLock()
+	info, ok := deprecations[key]
+	l := deprecationLogger
+	deprecationMu.RUnlock()
+	if !ok {
+		return []string{key}
+	}
+	if l != nil && atomic.CompareAndSwapInt32(&info.warned, 0, 1) {
+		l.Printf("%s", info.warningMessage(key))
+	}
+	return info.newKeys
+}
+
+// actualKey 返回读取key时实际使用的key
+func (x *XmlConfig) actualKey(key string) string {
+	newKeys := handleDeprecation(key)
+	if newKeys[0] == key {
+		return key
+	}
+	for _, k := range newKeys {
+		if _, ok := x.configurations[k]; ok {
+			return k
+		}
+	}
+	if _, ok := x.configurations[key]; ok {
+		return key
+	}
+	return newKeys[0]
+}
+
+// GetDeprecatedKeysInUse 返回已加载的配置文件中仍在使用的弃用key
+func (x *XmlConfig) GetDeprecatedKeysInUse() []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	keys := make([]string, 0, len(x.deprecatedInUse))
+	for k := range x.deprecatedInUse {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addDeprecatedInUse 记录配置文件中使用的弃用key
+func (x *XmlConfig) addDeprecatedInUse(key string) {
+	if x.deprecatedInUse == nil {
+		x.deprecatedInUse = make(map[string]bool)
+	}
+	x.deprecatedInUse[key] = true
+}
+
+func removeString(s []string, v string) []string {
+	r := s[:0]
+	for _, e := range s {
+		if e != v {
+			r = append(r, e)
+		}
+	}
+	return r
+}
@@ -0,0 +1,127 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLogger struct {
+	messages []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+// useDeprecations 注册deltas, 测试结束后恢复原有的弃用key
+func useDeprecations(t *testing.T, deltas []DeprecationDelta) {
+	deprecationMu.Lock()
+	saved, savedReverse := deprecations, reverseDeprecations
+	deprecations = make(map[string]*deprecatedKeyInfo, len(saved))
+	for k, v := range saved {
+		deprecations[k] = v
+	}
+	reverseDeprecations = make(map[string][]string, len(savedReverse))
+	for k, v := range savedReverse {
+		reverseDeprecations[k] = append([]string(nil), v...)
+	}
+	deprecationMu.Unlock()
+	t.Cleanup(func() {
+		deprecationMu.Lock()
+		defer deprecationMu.Unlock()
+		deprecations, reverseDeprecations = saved, savedReverse
+	})
+	AddDeprecations(deltas)
+}
+
+func TestXmlConfig_Deprecation(t *testing.T) {
+	deprecationMu.RLock()
+	prev := deprecationLogger
+	deprecationMu.RUnlock()
+	l := &testLogger{}
+	SetDeprecationLogger(l)
+	defer SetDeprecationLogger(prev)
+	useDeprecations(t, []DeprecationDelta{
+		{Key: "test.old.key", NewKeys: []string{"test.new.key"}},
+		{Key: "test.old.multi", NewKeys: []string{"test.new.a", "test.new.b"}, Message: "test.old.multi is gone"},
+	})
+
+	x := NewXmlConfig()
+	assert.Nil(t, x.ParseXmlData([]byte(`<configuration>
+    <property><name>test.old.key</name><value>1</value></property>
+    <property><name>test.old.multi</name><value>2</value></property>
+    <property><name>dfs.replication</name><value>3</value></property>
+</configuration>`)))
+	assert.Equal(t, []string{"test.new.key", "test.new.a", "test.new.b", "dfs.replication"}, x.GetConfigKeys())
+	assert.Equal(t, []string{"test.old.key", "test.old.multi"}, x.GetDeprecatedKeysInUse())
+	assert.Equal(t, []string{"test.old.key is deprecated. Instead, use test.new.key", "test.old.multi is gone"}, l.messages)
+
+	value, err := x.Get("test.old.key")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", value)
+	assert.Equal(t, "2", x.GetString("test.old.multi", ""))
+
+	x.SetString("test.old.multi", "20")
+	assert.Equal(t, "20", x.GetString("test.new.a", ""))
+	assert.Equal(t, "20", x.GetString("test.new.b", ""))
+	x.SetString("ref", "${test.old.key}")
+	assert.Equal(t, "1", x.GetString("ref", ""))
+
+	x.unset("test.old.key")
+	_, err = x.Get("test.new.key")
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(l.messages))
+
+	assert.False(t, IsDeprecated("fs.default.name"))
+	useDeprecations(t, HadoopDeprecations)
+	assert.True(t, IsDeprecated("fs.default.name"))
+	assert.False(t, IsDeprecated("fs.defaultFS"))
+	assert.Equal(t, []string{"fs.default.name"}, GetDeprecatedKeys("fs.defaultFS"))
+}
+
+func TestXmlConfig_Deprecation_NotRegistered(t *testing.T) {
+	data := "<configuration>\n  <property><name>dfs.permissions</name><value>false</value></property>\n</configuration>"
+	x := NewXmlConfig()
+	assert.Nil(t, x.ParseXmlData([]byte(data)))
+	assert.Equal(t, []string{"dfs.permissions"}, x.GetConfigKeys())
+	assert.Empty(t, x.GetDeprecatedKeysInUse())
+}
+
+func TestXmlConfig_Deprecation_PreserveFormat(t *testing.T) {
+	useDeprecations(t, nil)
+	AddDeprecation("test.edit.old", "test.edit.new")
+	data := "<configuration>\n  <property><name>test.edit.old</name><value>1</value></property>\n</configuration>\n"
+	x := NewXmlConfig()
+	x.SetPreserveFormat(true)
+	assert.Nil(t, x.ParseXmlData([]byte(data)))
+	out, err := x.BuildXmlData()
+	assert.Nil(t, err)
+	assert.Equal(t, data, string(out))
+	x.SetString("test.edit.new", "2")
+	out, err = x.BuildXmlData()
+	assert.Nil(t, err)
+	assert.Equal(t, "<configuration>\n  <property><name>test.edit.old</name><value>2</value></property>\n</configuration>\n", string(out))
+}
@@ -0,0 +1,333 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DiffOptions 比较两个配置时的选项
+type DiffOptions struct {
+	// IgnorePrefixes 忽略以其中任一前缀开头的key
+	IgnorePrefixes []string
+	// IgnorePatterns 忽略匹配其中任一正则的key
+	IgnorePatterns []*regexp.Regexp
+	// Expand 为true时比较展开变量后的值, 否则比较原始值
+	Expand bool
+	// Normalize 为true时比较规范化后的值: 容量换算为字节(128m等于134217728), 时间换算为纳秒,
+	// 布尔值不区分大小写, 逗号分隔的列表去除元素两侧的空白, 描述合并连续的空白.
+	// 任一配置的schema中有key的类型时按该类型规范化, 否则两个值都能解析为同一种类型时才按该类型比较,
+	// 如5m和300s按时间相等, 5m和5242880按容量相等, 没有单位的数字不按时间处理
+	Normalize bool
+}
+
+// PropertyChange 一个key在两个配置中的差异, Fields为发生变化的字段, 取值为value、tags、description和final
+type PropertyChange struct {
+	Key    string   `json:"key"`
+	Old    Property `json:"old"`
+	New    Property `json:"new"`
+	Fields []string `json:"fields"`
+}
+
+// DiffResult 两个配置的差异, 各列表均按key排序
+type DiffResult struct {
+	Added   []Property       `json:"added"`
+	Removed []Property       `json:"removed"`
+	Changed []PropertyChange `json:"changed"`
+	// MetadataChanged 值相同但tag、描述或final标记不同的key
+	MetadataChanged []PropertyChange `json:"metadataChanged"`
+	// expanded 为true时文本中输出展开变量后的值
+	expanded bool
+}
+
+// Diff 比较两个配置的原始值和元数据, 返回b相对于a的差异
+func Diff(a, b *XmlConfig) *DiffResult {
+	return DiffWithOptions(a, b, DiffOptions{})
+}
+
+// DiffWithOptions 按opts比较两个配置, 返回b相对于a的差异
+func DiffWithOptions(a, b *XmlConfig, opts DiffOptions) *DiffResult {
+	oldProps, newProps := a.diffProperties(opts), b.diffProperties(opts)
+	keys := make([]string, 0, len(oldProps)+len(newProps))
+	for key := range oldProps {
+		keys = append(keys, key)
+	}
+	for key := range newProps {
+		if _, ok := oldProps[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	d := &DiffResult{
+		Added:           make([]Property, 0),
+		Removed:         make([]Property, 0),
+		Changed:         make([]PropertyChange, 0),
+		MetadataChanged: make([]PropertyChange, 0),
+		expanded:        opts.Expand,
+	}
+	for _, key := range keys {
+		o, inOld := oldProps[key]
+		n, inNew := newProps[key]
+		switch {
+		case !inOld:
+			d.Added = append(d.Added, n)
+		case !inNew:
+			d.Removed = append(d.Removed, o)
+		default:
+			fields := diffFields(o, n, opts, valueType(a, b, key))
+			if len(fields) == 0 {
+				continue
+			}
+			c := PropertyChange{Key: key, Old: o, New: n, Fields: fields}
+			if fields[0] == "value" {
+				d.Changed = append(d.Changed, c)
+			} else {
+				d.MetadataChanged = append(d.MetadataChanged, c)
+			}
+		}
+	}
+	return d
+}
+
+// diffProperties 返回未被忽略的所有配置项
+func (x *XmlConfig) diffProperties(opts DiffOptions) map[string]Property {
+	props := make(map[string]Property)
+	for _, p := range x.Properties() {
+		if !opts.ignored(p.Name) {
+			props[p.Name] = p
+		}
+	}
+	return props
+}
+
+// ignored 判断key是否被忽略
+func (opts DiffOptions) ignored(key string) bool {
+	for _, prefix := range opts.IgnorePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	for _, re := range opts.IgnorePatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// value 返回用于比较的值
+func (opts DiffOptions) value(p Property) string {
+	if opts.Expand {
+		return p.Value
+	}
+	return p.RawValue
+}
+
+// valueType 返回a或b的schema中key的类型, 都没有时返回空
+func valueType(a, b *XmlConfig, key string) ValueType {
+	for _, x := range []*XmlConfig{a, b} {
+		x.mu.RLock()
+		s := x.schema
+		x.mu.RUnlock()
+		if s == nil {
+			continue
+		}
+		if c, ok := s.index[key]; ok {
+			return c.Type
+		}
+	}
+	return ""
+}
+
+// diffFields 返回o和n之间发生变化的字段, value总是排在第一个, t为schema中key的类型
+func diffFields(o, n Property, opts DiffOptions, t ValueType) []string {
+	var fields []string
+	if ov, nv := opts.value(o), opts.value(n); ov != nv && (!opts.Normalize || !equalValues(ov, nv, t)) {
+		fields = append(fields, "value")
+	}
+	if !equalTags(o.Tags, n.Tags) {
+		fields = append(fields, "tags")
+	}
+	od, nd := o.Description, n.Description
+	if opts.Normalize {
+		od, nd = strings.Join(strings.Fields(od), " "), strings.Join(strings.Fields(nd), " ")
+	}
+	if od != nd {
+		fields = append(fields, "description")
+	}
+	if o.Final != n.Final {
+		fields = append(fields, "final")
+	}
+	return fields
+}
+
+// equalTags 不考虑顺序比较两组tag
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// inferredTypes 没有schema时尝试的类型
+var inferredTypes = []ValueType{TypeStorageSize, TypeDuration, TypeBool}
+
+// equalValues 判断a和b规范化后是否相等. t不为空时按t规范化, 否则两个值都能解析为同一种类型且按该类型相等时返回true,
+// 此时没有单位的数字不按时间处理
+func equalValues(a, b string, t ValueType) bool {
+	types, unit := inferredTypes, time.Duration(0)
+	if t != "" {
+		types, unit = []ValueType{t}, time.Millisecond
+	}
+	for _, t := range types {
+		na, okA := normalizeValue(a, t, unit)
+		nb, okB := normalizeValue(b, t, unit)
+		if okA && okB && na == nb {
+			return true
+		}
+	}
+	na, _ := normalizeValue(a, TypeString, unit)
+	nb, _ := normalizeValue(b, TypeString, unit)
+	return na == nb
+}
+
+// normalizeValue 按t规范化配置值, 无法解析为t时返回false. unit为没有单位的时间的单位, 为0时不接受没有单位的时间
+func normalizeValue(v string, t ValueType, unit time.Duration) (string, bool) {
+	v = strings.TrimSpace(v)
+	switch t {
+	case TypeStorageSize:
+		b, err := parseBytes(v, Byte)
+		return fmt.Sprintf("%d", b), err == nil
+	case TypeDuration:
+		d, err := parseDuration(v, unit)
+		return fmt.Sprintf("%dns", d), err == nil
+	case TypeBool:
+		return strings.ToLower(v), strings.EqualFold(v, "true") || strings.EqualFold(v, "false")
+	case TypeInt:
+		i, err := strconv.ParseInt(v, 10, 64)
+		return strconv.FormatInt(i, 10), err == nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(v, 64)
+		return strconv.FormatFloat(f, 'g', -1, 64), err == nil
+	}
+	if strings.Contains(v, ",") {
+		parts := strings.Split(v, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return strings.Join(parts, ","), true
+	}
+	return v, true
+}
+
+// Empty 判断两个配置是否没有差异
+func (d *DiffResult) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && len(d.MetadataChanged) == 0
+}
+
+// JSON 以json格式返回差异
+func (d *DiffResult) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// shown 返回文本中输出的值
+func (d *DiffResult) shown(p Property) string {
+	if d.expanded {
+		return escapeLine(p.Value)
+	}
+	return escapeLine(p.RawValue)
+}
+
+// escapeLine 包含换行等控制字符的文本以Go字符串字面量输出, 保证每个值只占一行
+func escapeLine(s string) string {
+	if strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// Unified 以类似unified diff的文本返回差异, from和to为两个配置的名称.
+// 新增和删除的key以+和-开头, 值的变化输出为一对-/+行, 元数据的变化输出在key下方并缩进.
+// 包含换行等控制字符的值和描述以带引号的转义形式输出
+func (d *DiffResult) Unified(from, to string) string {
+	type entry struct {
+		key   string
+		lines []string
+	}
+	var entries []entry
+	for _, p := range d.Added {
+		entries = append(entries, entry{p.Name, []string{"+" + p.Name + "=" + d.shown(p)}})
+	}
+	for _, p := range d.Removed {
+		entries = append(entries, entry{p.Name, []string{"-" + p.Name + "=" + d.shown(p)}})
+	}
+	for _, c := range append(append([]PropertyChange(nil), d.Changed...), d.MetadataChanged...) {
+		var lines []string
+		if c.Fields[0] == "value" {
+			lines = append(lines, "-"+c.Key+"="+d.shown(c.Old), "+"+c.Key+"="+d.shown(c.New))
+		} else {
+			lines = append(lines, " "+c.Key+"="+d.shown(c.New))
+		}
+		for _, f := range c.Fields {
+			var o, n string
+			switch f {
+			case "tags":
+				o, n = strings.Join(c.Old.Tags, ","), strings.Join(c.New.Tags, ",")
+			case "description":
+				o, n = c.Old.Description, c.New.Description
+			case "final":
+				o, n = fmt.Sprint(c.Old.Final), fmt.Sprint(c.New.Final)
+			default:
+				continue
+			}
+			lines = append(lines, "-  "+f+": "+escapeLine(o), "+  "+f+": "+escapeLine(n))
+		}
+		entries = append(entries, entry{c.Key, lines})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+	for _, e := range entries {
+		for _, l := range e.lines {
+			b.WriteString(l)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,234 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const diffOld = `<configuration>
+    <property><name>dfs.replication</name><value>3</value></property>
+    <property><name>dfs.blocksize</name><value>128m</value></property>
+    <property><name>dfs.heartbeat.interval</name><value>3s</value></property>
+    <property><name>dfs.datanode.data.dir</name><value>/data/1, /data/2</value><tag>HDFS</tag></property>
+    <property><name>hadoop.tmp.dir</name><value>/tmp/${user}</value></property>
+    <property><name>user</name><value>hdfs</value></property>
+    <property><name>yarn.nodemanager.address</name><value>0.0.0.0:8041</value></property>
+    <property><name>ha.zookeeper.quorum</name><value>zk1:2181</value><description>ZK ensemble</description></property>
+</configuration>`
+
+const diffNew = `<configuration>
+    <property><name>dfs.replication</name><value>2</value></property>
+    <property><name>dfs.blocksize</name><value>134217728</value></property>
+    <property><name>dfs.heartbeat.interval</name><value>3000</value></property>
+    <property><name>dfs.datanode.data.dir</name><value>/data/1,/data/2</value><tag>HDFS,REQUIRED</tag></property>
+    <property><name>hadoop.tmp.dir</name><value>/tmp/hdfs</value></property>
+    <property><name>user</name><value>hdfs</value></property>
+    <property><name>dfs.namenode.rpc-address</name><value>nn:8020</value></property>
+    <property><name>ha.zookeeper.quorum</name><value>zk1:2181</value><description>ZK   ensemble</description><final>true</final></property>
+</configuration>`
+
+func newDiffCase(t *testing.T) (*XmlConfig, *XmlConfig) {
+	a, b := NewXmlConfig(), NewXmlConfig()
+	assert.Nil(t, a.AddResourceData("old", []byte(diffOld)))
+	assert.Nil(t, b.AddResourceData("new", []byte(diffNew)))
+	return a, b
+}
+
+func TestDiff(t *testing.T) {
+	a, b := newDiffCase(t)
+	keys := func(props []Property) []string {
+		var ks []string
+		for _, p := range props {
+			ks = append(ks, p.Name)
+		}
+		return ks
+	}
+	changes := func(cs []PropertyChange) map[string][]string {
+		m := make(map[string][]string)
+		for _, c := range cs {
+			m[c.Key] = c.Fields
+		}
+		return m
+	}
+	tests := []struct {
+		name        string
+		opts        DiffOptions
+		wantAdded   []string
+		wantRemoved []string
+		wantChanged map[string][]string
+		wantMeta    map[string][]string
+	}{
+		{
+			name:        "原始值",
+			wantAdded:   []string{"dfs.namenode.rpc-address"},
+			wantRemoved: []string{"yarn.nodemanager.address"},
+			wantChanged: map[string][]string{
+				"dfs.blocksize":          {"value"},
+				"dfs.datanode.data.dir":  {"value", "tags"},
+				"dfs.heartbeat.interval": {"value"},
+				"dfs.replication":        {"value"},
+				"hadoop.tmp.dir":         {"value"},
+			},
+			wantMeta: map[string][]string{"ha.zookeeper.quorum": {"description", "final"}},
+		},
+		{
+			name:        "展开并规范化",
+			opts:        DiffOptions{Expand: true, Normalize: true},
+			wantAdded:   []string{"dfs.namenode.rpc-address"},
+			wantRemoved: []string{"yarn.nodemanager.address"},
+			wantChanged: map[string][]string{
+				"dfs.heartbeat.interval": {"value"},
+				"dfs.replication":        {"value"},
+			},
+			wantMeta: map[string][]string{
+				"dfs.datanode.data.dir": {"tags"},
+				"ha.zookeeper.quorum":   {"final"},
+			},
+		},
+		{
+			name: "忽略前缀和正则",
+			opts: DiffOptions{
+				Normalize:      true,
+				IgnorePrefixes: []string{"yarn.", "ha."},
+				IgnorePatterns: []*regexp.Regexp{regexp.MustCompile(`^dfs\.(namenode|heartbeat)\.`)},
+			},
+			wantChanged: map[string][]string{
+				"dfs.replication": {"value"},
+				"hadoop.tmp.dir":  {"value"},
+			},
+			wantMeta: map[string][]string{"dfs.datanode.data.dir": {"tags"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DiffWithOptions(a, b, tt.opts)
+			assert.Equal(t, tt.wantAdded, keys(d.Added))
+			assert.Equal(t, tt.wantRemoved, keys(d.Removed))
+			assert.Equal(t, tt.wantChanged, changes(d.Changed))
+			assert.Equal(t, tt.wantMeta, changes(d.MetadataChanged))
+		})
+	}
+	assert.True(t, Diff(a, a.Clone()).Empty())
+	assert.False(t, Diff(a, b).Empty())
+}
+
+func TestDiffResult_Unified(t *testing.T) {
+	a, b := newDiffCase(t)
+	d := DiffWithOptions(a, b, DiffOptions{Expand: true, Normalize: true})
+	want := "--- old\n" +
+		"+++ new\n" +
+		" dfs.datanode.data.dir=/data/1,/data/2\n" +
+		"-  tags: HDFS\n" +
+		"+  tags: HDFS,REQUIRED\n" +
+		"-dfs.heartbeat.interval=3s\n" +
+		"+dfs.heartbeat.interval=3000\n" +
+		"+dfs.namenode.rpc-address=nn:8020\n" +
+		"-dfs.replication=3\n" +
+		"+dfs.replication=2\n" +
+		" ha.zookeeper.quorum=zk1:2181\n" +
+		"-  final: false\n" +
+		"+  final: true\n" +
+		"-yarn.nodemanager.address=0.0.0.0:8041\n"
+	assert.Equal(t, want, d.Unified("old", "new"))
+}
+
+func TestDiffResult_JSON(t *testing.T) {
+	a, b := newDiffCase(t)
+	data, err := Diff(a, b).JSON()
+	assert.Nil(t, err)
+	var got struct {
+		Added []struct {
+			Name   string `json:"name"`
+			Value  string `json:"value"`
+			Source string `json:"source"`
+		} `json:"added"`
+		Changed []struct {
+			Key    string   `json:"key"`
+			Fields []string `json:"fields"`
+			New    struct {
+				RawValue string `json:"rawValue"`
+			} `json:"new"`
+		} `json:"changed"`
+		MetadataChanged []json.RawMessage `json:"metadataChanged"`
+	}
+	assert.Nil(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "dfs.namenode.rpc-address", got.Added[0].Name)
+	assert.Equal(t, "nn:8020", got.Added[0].Value)
+	assert.Equal(t, "new", got.Added[0].Source)
+	assert.Equal(t, "dfs.blocksize", got.Changed[0].Key)
+	assert.Equal(t, "134217728", got.Changed[0].New.RawValue)
+	assert.Len(t, got.MetadataChanged, 1)
+
+	data, err = Diff(a, a).JSON()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"added":[],"removed":[],"changed":[],"metadataChanged":[]}`, string(data))
+}
+
+func TestDiffWithOptions_NormalizeType(t *testing.T) {
+	durationSchema, err := NewSchema(KeySchema{Name: "k", Type: TypeDuration})
+	assert.Nil(t, err)
+	tests := []struct {
+		name        string
+		old, new    string
+		schema      *Schema
+		wantChanged bool
+	}{
+		{name: "时间", old: "5m", new: "300s"},
+		{name: "容量", old: "5m", new: "5242880"},
+		{name: "不同类型", old: "5242880", new: "300s", wantChanged: true},
+		{name: "没有单位的数字不按时间处理", old: "3s", new: "3000", wantChanged: true},
+		{name: "schema为时间", old: "3s", new: "3000", schema: durationSchema},
+		{name: "schema为时间时不按容量处理", old: "5m", new: "5242880", schema: durationSchema, wantChanged: true},
+		{name: "布尔值", old: "TRUE", new: "true"},
+		{name: "列表", old: "a, b", new: "a,b"},
+		{name: "字符串", old: "a", new: "b", wantChanged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := NewXmlConfig(), NewXmlConfig()
+			a.SetString("k", tt.old)
+			b.SetString("k", tt.new)
+			b.SetSchema(tt.schema)
+			d := DiffWithOptions(a, b, DiffOptions{Normalize: true})
+			assert.Equal(t, tt.wantChanged, len(d.Changed) == 1)
+		})
+	}
+}
+
+func TestDiffResult_Unified_MultiLine(t *testing.T) {
+	a, b := NewXmlConfig(), NewXmlConfig()
+	a.SetStringWithDescription("k", "a\nb", "line1\nline2")
+	b.SetStringWithDescription("k", "a\nb", "line1")
+	b.SetString("added", "x\n+y")
+	want := "--- old\n" +
+		"+++ new\n" +
+		"+added=\"x\\n+y\"\n" +
+		" k=\"a\\nb\"\n" +
+		"-  description: \"line1\\nline2\"\n" +
+		"+  description: line1\n"
+	assert.Equal(t, want, Diff(a, b).Unified("old", "new"))
+}
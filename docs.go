@@ -0,0 +1,251 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DocFormat 配置文档的格式
+type DocFormat int
+
+const (
+	// DocMarkdown Markdown表格
+	DocMarkdown DocFormat = iota
+	// DocHTML 与Hadoop的core-default.html类似的HTML表格
+	DocHTML
+	// DocCSV CSV, 分组时第一列为分组名
+	DocCSV
+)
+
+// DocGroupBy 配置文档的分组方式
+type DocGroupBy int
+
+const (
+	// GroupNone 不分组
+	GroupNone DocGroupBy = iota
+	// GroupByTag 按tag分组, 带有多个tag的配置会出现在每个分组中, 没有tag的配置在最后一组
+	GroupByTag
+	// GroupByPrefix 按key的前缀分组, 如dfs.replication属于dfs
+	GroupByPrefix
+)
+
+// DocOptions 生成配置文档的选项
+type DocOptions struct {
+	Format  DocFormat
+	GroupBy DocGroupBy
+	// PrefixDepth 按前缀分组时前缀包含的段数, 默认为1
+	PrefixDepth int
+	// Title 文档标题, 为空时不输出, CSV格式忽略
+	Title string
+}
+
+// docHeader 文档表格的列名
+var docHeader = []string{"Name", "Default Value", "Description", "Tags", "Deprecated Keys"}
+
+// docGroup 文档中的一组配置, 按key排序
+type docGroup struct {
+	name string
+	rows [][]string
+}
+
+// WriteDoc 以opts指定的格式将配置的名称、原始值、描述、tag和被其替代的弃用key写入w, 配置按key排序
+func (x *XmlConfig) WriteDoc(w io.Writer, opts DocOptions) error {
+	groups := x.docGroups(opts)
+	switch opts.Format {
+	case DocMarkdown:
+		return writeMarkdownDoc(w, opts.Title, groups)
+	case DocHTML:
+		return writeHTMLDoc(w, opts.Title, groups)
+	case DocCSV:
+		return writeCSVDoc(w, opts.GroupBy != GroupNone, groups)
+	}
+	return fmt.Errorf("unknown doc format: %d", opts.Format)
+}
+
+// docGroups 按opts分组并排序配置
+func (x *XmlConfig) docGroups(opts DocOptions) []docGroup {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	keys := make([]string, 0, len(x.configurations))
+	for key := range x.configurations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	index := make(map[string]int)
+	var groups []docGroup
+	add := func(name string, row []string) {
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, docGroup{name: name})
+		}
+		groups[i].rows = append(groups[i].rows, row)
+	}
+	for _, key := range keys {
+		p := x.configurations[key]
+		row := []string{
+			p.Name,
+			p.Value,
+			strings.Join(strings.Fields(p.Description), " "),
+			strings.Join(splitTags(p.Tag), ", "),
+			strings.Join(GetDeprecatedKeys(key), ", "),
+		}
+		switch opts.GroupBy {
+		case GroupByTag:
+			tags := splitTags(p.Tag)
+			if len(tags) == 0 {
+				add("", row)
+			}
+			for _, tag := range tags {
+				add(tag, row)
+			}
+		case GroupByPrefix:
+			add(keyPrefix(key, opts.PrefixDepth), row)
+		default:
+			add("", row)
+		}
+	}
+	// 空分组名排在最后
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].name == "" || groups[j].name == "" {
+			return groups[j].name == ""
+		}
+		return groups[i].name < groups[j].name
+	})
+	return groups
+}
+
+// keyPrefix 返回key的前depth段, key的段数不超过depth时返回除最后一段外的部分
+func keyPrefix(key string, depth int) string {
+	if depth <= 0 {
+		depth = 1
+	}
+	parts := strings.Split(key, ".")
+	if len(parts) <= depth {
+		depth = len(parts) - 1
+	}
+	return strings.Join(parts[:depth], ".")
+}
+
+// groupTitle 返回分组的标题, 空分组名在只有一个分组时不输出标题
+func groupTitle(g docGroup, groups []docGroup) string {
+	if g.name != "" {
+		return g.name
+	}
+	if len(groups) > 1 {
+		return "other"
+	}
+	return ""
+}
+
+func writeMarkdownDoc(w io.Writer, title string, groups []docGroup) error {
+	var b strings.Builder
+	if title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", markdownEscape(title))
+	}
+	for _, g := range groups {
+		if t := groupTitle(g, groups); t != "" {
+			fmt.Fprintf(&b, "## %s\n\n", markdownEscape(t))
+		}
+		b.WriteString("| " + strings.Join(docHeader, " | ") + " |\n")
+		b.WriteString(strings.Repeat("| --- ", len(docHeader)) + "|\n")
+		for _, row := range g.rows {
+			cells := make([]string, len(row))
+			for i, c := range row {
+				cells[i] = markdownEscape(c)
+			}
+			b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscape 转义Markdown表格中有特殊含义的字符
+func markdownEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "|", "\\|", "\n", " ", "*", "\\*", "_", "\\_", "`", "\\`", "<", "&lt;").Replace(s)
+}
+
+func writeHTMLDoc(w io.Writer, title string, groups []docGroup) error {
+	var b strings.Builder
+	b.WriteString("<html>\n<body>\n")
+	if title != "" {
+		fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(title))
+	}
+	for _, g := range groups {
+		if t := groupTitle(g, groups); t != "" {
+			fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(t))
+		}
+		b.WriteString("<table border=\"1\">\n<tr>")
+		for _, h := range docHeader {
+			fmt.Fprintf(&b, "<th>%s</th>", h)
+		}
+		b.WriteString("</tr>\n")
+		for _, row := range g.rows {
+			b.WriteString("<tr>")
+			for i, c := range row {
+				if i == 0 {
+					fmt.Fprintf(&b, "<td><a name=\"%s\">%s</a></td>", html.EscapeString(c), html.EscapeString(c))
+				} else {
+					fmt.Fprintf(&b, "<td>%s</td>", html.EscapeString(c))
+				}
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</table>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeCSVDoc(w io.Writer, grouped bool, groups []docGroup) error {
+	cw := csv.NewWriter(w)
+	header := docHeader
+	if grouped {
+		header = append([]string{"Group"}, header...)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		for _, row := range g.rows {
+			if grouped {
+				row = append([]string{g.name}, row...)
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
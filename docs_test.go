@@ -0,0 +1,125 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const docSite = `<configuration>
+    <property>
+        <name>fs.defaultFS</name>
+        <value>file:///</value>
+        <tag>CORE</tag>
+        <description>The name of the default
+            file system.</description>
+    </property>
+    <property>
+        <name>dfs.replication</name>
+        <value>3</value>
+        <tag>HDFS,REQUIRED</tag>
+        <description>Default block replication | count.</description>
+    </property>
+    <property>
+        <name>hadoop.tmp.dir</name>
+        <value>/tmp/hadoop-${user.name}</value>
+        <description>A base for other temporary directories.</description>
+    </property>
+</configuration>`
+
+func TestXmlConfig_WriteDoc(t *testing.T) {
+	useDeprecations(t, HadoopDeprecations)
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-default.xml", []byte(docSite)))
+	tests := []struct {
+		name string
+		opts DocOptions
+		want string
+	}{
+		{
+			name: "Markdown",
+			opts: DocOptions{Format: DocMarkdown, Title: "core-default"},
+			want: "# core-default\n\n" +
+				"| Name | Default Value | Description | Tags | Deprecated Keys |\n" +
+				"| --- | --- | --- | --- | --- |\n" +
+				"| dfs.replication | 3 | Default block replication \\| count. | HDFS, REQUIRED |  |\n" +
+				"| fs.defaultFS | file:/// | The name of the default file system. | CORE | fs.default.name |\n" +
+				"| hadoop.tmp.dir | /tmp/hadoop-${user.name} | A base for other temporary directories. |  |  |\n\n",
+		},
+		{
+			name: "按tag分组的CSV",
+			opts: DocOptions{Format: DocCSV, GroupBy: GroupByTag},
+			want: "Group,Name,Default Value,Description,Tags,Deprecated Keys\n" +
+				"CORE,fs.defaultFS,file:///,The name of the default file system.,CORE,fs.default.name\n" +
+				"HDFS,dfs.replication,3,Default block replication | count.,\"HDFS, REQUIRED\",\n" +
+				"REQUIRED,dfs.replication,3,Default block replication | count.,\"HDFS, REQUIRED\",\n" +
+				",hadoop.tmp.dir,/tmp/hadoop-${user.name},A base for other temporary directories.,,\n",
+		},
+		{
+			name: "按前缀分组的CSV",
+			opts: DocOptions{Format: DocCSV, GroupBy: GroupByPrefix},
+			want: "Group,Name,Default Value,Description,Tags,Deprecated Keys\n" +
+				"dfs,dfs.replication,3,Default block replication | count.,\"HDFS, REQUIRED\",\n" +
+				"fs,fs.defaultFS,file:///,The name of the default file system.,CORE,fs.default.name\n" +
+				"hadoop,hadoop.tmp.dir,/tmp/hadoop-${user.name},A base for other temporary directories.,,\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.Nil(t, x.WriteDoc(&buf, tt.opts))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, x.WriteDoc(&buf, DocOptions{Format: DocHTML, GroupBy: GroupByTag, Title: "a<b"}))
+	doc := buf.String()
+	assert.Contains(t, doc, "<h1>a&lt;b</h1>")
+	assert.Contains(t, doc, "<h2>CORE</h2>")
+	assert.Contains(t, doc, "<h2>other</h2>")
+	assert.Contains(t, doc, `<td><a name="fs.defaultFS">fs.defaultFS</a></td><td>file:///</td>`)
+	assert.Equal(t, 4, strings.Count(doc, "<table"))
+	assert.True(t, strings.Index(doc, "<h2>REQUIRED</h2>") < strings.Index(doc, "<h2>other</h2>"))
+
+	assert.NotNil(t, x.WriteDoc(&buf, DocOptions{Format: DocFormat(10)}))
+}
+
+func TestKeyPrefix(t *testing.T) {
+	tests := []struct {
+		key   string
+		depth int
+		want  string
+	}{
+		{"dfs.namenode.name.dir", 0, "dfs"},
+		{"dfs.namenode.name.dir", 2, "dfs.namenode"},
+		{"dfs.replication", 2, "dfs"},
+		{"replication", 1, ""},
+	}
+	for _, tt := range tests {
+		assert.Equalf(t, tt.want, keyPrefix(tt.key, tt.depth), "keyPrefix(%v, %v)", tt.key, tt.depth)
+	}
+}
@@ -0,0 +1,259 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrMultipleDocuments 编辑模式下解析过多个xml文档, 无法在单个原始文档上输出配置
+var ErrMultipleDocuments = errors.New("preserve format does not support multiple xml documents")
+
+// document 编辑模式下保留的原始xml文档
+type document struct {
+	data       []byte
+	nodes      []docNode
+	closeStart int // </configuration>的起始位置, 不存在时为-1
+	// layered 为true时配置中还有来自其他xml文档的内容
+	layered bool
+}
+
+// docNode 原始文档中的一个<property>节点
+type docNode struct {
+	start, end           int // <property>元素在文档中的范围
+	valueStart, valueEnd int // <value>内容在文档中的范围, 无法原地替换时valueStart为-1
+	orig                 property
+}
+
+// SetPreserveFormat 设置是否开启编辑模式. 开启后解析的xml文档会被保留,
+// BuildXmlData只改写被修改或删除的<property>节点并将新增的key追加在末尾,
+// 注释、处理指令和格式等其余内容原样输出. 编辑模式面向单个文档, 资源栈中有多个xml文档或
+// 解析过多个xml数据时BuildXmlData返回ErrMultipleDocuments, Reload之后只计算资源栈中的文档
+func (x *XmlConfig) SetPreserveFormat(preserve bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.preserveFormat = preserve
+	if !preserve {
+		x.doc = nil
+	}
+}
+
+// parseDocument 解析xml文档中各<property>节点的位置
+func parseDocument(data []byte) (*document, error) {
+	doc := &document{data: data, closeStart: -1}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	var cur *docNode
+	for {
+		off := int(d.InputOffset())
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 && t.Name.Local == "property" {
+				cur = &docNode{start: off, valueStart: -1, valueEnd: -1}
+			} else if depth == 3 && cur != nil && t.Name.Local == "value" {
+				cur.valueStart = int(d.InputOffset())
+				if bytes.HasSuffix(data[:cur.valueStart], []byte("/>")) {
+					cur.valueStart = -1
+				}
+			}
+		case xml.EndElement:
+			if depth == 3 && cur != nil && t.Name.Local == "value" && cur.valueStart >= 0 {
+				cur.valueEnd = off
+			} else if depth == 2 && cur != nil && t.Name.Local == "property" {
+				cur.end = int(d.InputOffset())
+				if err := xml.Unmarshal(data[cur.start:cur.end], &cur.orig); err != nil {
+					return nil, err
+				}
+				doc.nodes = append(doc.nodes, *cur)
+				cur = nil
+			} else if depth == 1 && !bytes.HasSuffix(data[:d.InputOffset()], []byte("/>")) {
+				doc.closeStart = off
+			}
+			depth--
+		}
+	}
+	return doc, nil
+}
+
+// buildDocument 在保留的原始文档上应用修改
+func (x *XmlConfig) buildDocument() ([]byte, error) {
+	doc := x.doc
+	if doc.layered {
+		return nil, ErrMultipleDocuments
+	}
+	data := doc.data
+	// 弃用的key在解析时已被转换为新key
+	names := make([]string, len(doc.nodes))
+	last := make(map[string]int, len(doc.nodes))
+	for i, n := range doc.nodes {
+		names[i] = handleDeprecation(n.orig.Name)[0]
+		last[names[i]] = i
+	}
+	var newKeys []string
+	for _, key := range x.orderedKeys() {
+		if _, ok := last[key]; !ok {
+			newKeys = append(newKeys, key)
+		}
+	}
+	if len(newKeys) > 0 && doc.closeStart < 0 {
+		return x.buildXmlData()
+	}
+
+	var b bytes.Buffer
+	pos := 0
+	for i, n := range doc.nodes {
+		p, ok := x.configurations[names[i]]
+		switch {
+		case !ok:
+			start, end := lineBounds(data, n.start, n.end)
+			b.Write(data[pos:start])
+			pos = end
+		case i != last[names[i]]:
+		case p.Value == n.orig.Value && p.Tag == n.orig.Tag && p.Description == n.orig.Description && p.Final == n.orig.Final:
+		case p.Tag == n.orig.Tag && p.Description == n.orig.Description && p.Final == n.orig.Final && n.valueStart >= 0:
+			b.Write(data[pos:n.valueStart])
+			if err := xml.EscapeText(&b, []byte(p.Value)); err != nil {
+				return nil, err
+			}
+			pos = n.valueEnd
+		default:
+			b.Write(data[pos:n.start])
+			indent, unit, multiline := nodeStyle(data, n)
+			if err := writeProperty(&b, p, indent, unit, multiline); err != nil {
+				return nil, err
+			}
+			pos = n.end
+		}
+	}
+	if len(newKeys) > 0 {
+		indent, unit, multiline := "    ", "    ", true
+		if len(doc.nodes) > 0 {
+			indent, unit, multiline = nodeStyle(data, doc.nodes[len(doc.nodes)-1])
+		}
+		insert, prefix := doc.closeStart, "\n"+indent
+		if start, ok := lineStart(data, doc.closeStart); ok {
+			insert, prefix = start, indent
+		}
+		b.Write(data[pos:insert])
+		pos = insert
+		for _, key := range newKeys {
+			b.WriteString(prefix)
+			if err := writeProperty(&b, x.configurations[key], indent, unit, multiline); err != nil {
+				return nil, err
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.Write(data[pos:])
+	return b.Bytes(), nil
+}
+
+// lineStart 当pos之前同一行只有空白时返回行首位置
+func lineStart(data []byte, pos int) (int, bool) {
+	s := pos
+	for s > 0 && (data[s-1] == ' ' || data[s-1] == '\t') {
+		s--
+	}
+	return s, s == 0 || data[s-1] == '\n'
+}
+
+// lineBounds 当[start,end)所在行除空白外没有其他内容时, 将范围扩展到整行(含换行符)
+func lineBounds(data []byte, start, end int) (int, int) {
+	s, ok := lineStart(data, start)
+	if !ok {
+		return start, end
+	}
+	e := end
+	for e < len(data) && (data[e] == ' ' || data[e] == '\t' || data[e] == '\r') {
+		e++
+	}
+	if e < len(data) && data[e] != '\n' {
+		return start, end
+	}
+	if e < len(data) {
+		e++
+	}
+	return s, e
+}
+
+// nodeStyle 返回节点的缩进、子元素的缩进单位以及是否为多行格式
+func nodeStyle(data []byte, n docNode) (indent, unit string, multiline bool) {
+	s, _ := lineStart(data, n.start)
+	indent = string(data[s:n.start])
+	unit = "    "
+	body := data[n.start:n.end]
+	i := bytes.IndexByte(body, '\n')
+	if i < 0 {
+		return indent, unit, false
+	}
+	j := i + 1
+	for j < len(body) && (body[j] == ' ' || body[j] == '\t') {
+		j++
+	}
+	if child := string(body[i+1 : j]); strings.HasPrefix(child, indent) && len(child) > len(indent) {
+		unit = child[len(indent):]
+	}
+	return indent, unit, true
+}
+
+// writeProperty 按给定格式输出<property>节点, 第一行不含缩进
+func writeProperty(b *bytes.Buffer, p *property, indent, unit string, multiline bool) error {
+	sep, end := "", ""
+	if multiline {
+		sep, end = "\n"+indent+unit, "\n"+indent
+	}
+	elems := []struct {
+		name, value string
+		keep        bool
+	}{
+		{"name", p.Name, true},
+		{"value", p.Value, true},
+		{"tag", p.Tag, p.Tag != ""},
+		{"description", p.Description, p.Description != ""},
+		{"final", "true", p.Final},
+	}
+	b.WriteString("<property>")
+	for _, e := range elems {
+		if !e.keep {
+			continue
+		}
+		b.WriteString(sep + "<" + e.name + ">")
+		if err := xml.EscapeText(b, []byte(e.value)); err != nil {
+			return err
+		}
+		b.WriteString("</" + e.name + ">")
+	}
+	b.WriteString(end + "</property>")
+	return nil
+}
@@ -0,0 +1,235 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const editCase = `<?xml version="1.0" encoding="UTF-8"?>
+<?xml-stylesheet type="text/xsl" href="configuration.xsl"?>
+<!--
+  Licensed under the Apache License, Version 2.0.
+-->
+<configuration>
+  <!-- namenode -->
+  <property>
+    <name>fs.defaultFS</name>
+    <value>hdfs://nn:8020</value>   <!-- keep me -->
+    <description>The default file system &amp; URI.</description>
+  </property>
+
+  <property><name>dfs.replication</name><value>3</value></property>
+  <property>
+    <name>hadoop.tmp.dir</name>
+    <value/>
+  </property>
+</configuration>
+`
+
+func TestXmlConfig_BuildXmlData_PreserveFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(x *XmlConfig)
+		want   string
+	}{
+		{
+			name:   "未修改时原样输出",
+			modify: func(x *XmlConfig) {},
+			want:   editCase,
+		},
+		{
+			name: "修改value",
+			modify: func(x *XmlConfig) {
+				x.SetString("fs.defaultFS", "hdfs://a&b:8020")
+				x.SetString("dfs.replication", "2")
+			},
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<?xml-stylesheet type="text/xsl" href="configuration.xsl"?>
+<!--
+  Licensed under the Apache License, Version 2.0.
+-->
+<configuration>
+  <!-- namenode -->
+  <property>
+    <name>fs.defaultFS</name>
+    <value>hdfs://a&amp;b:8020</value>   <!-- keep me -->
+    <description>The default file system &amp; URI.</description>
+  </property>
+
+  <property><name>dfs.replication</name><value>2</value></property>
+  <property>
+    <name>hadoop.tmp.dir</name>
+    <value/>
+  </property>
+</configuration>
+`,
+		},
+		{
+			name: "删除节点",
+			modify: func(x *XmlConfig) {
+				x.unset("dfs.replication")
+			},
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<?xml-stylesheet type="text/xsl" href="configuration.xsl"?>
+<!--
+  Licensed under the Apache License, Version 2.0.
+-->
+<configuration>
+  <!-- namenode -->
+  <property>
+    <name>fs.defaultFS</name>
+    <value>hdfs://nn:8020</value>   <!-- keep me -->
+    <description>The default file system &amp; URI.</description>
+  </property>
+
+  <property>
+    <name>hadoop.tmp.dir</name>
+    <value/>
+  </property>
+</configuration>
+`,
+		},
+		{
+			name: "重写节点并新增key",
+			modify: func(x *XmlConfig) {
+				x.SetString("hadoop.tmp.dir", "/tmp")
+				x.SetString("io.file.buffer.size", "65536")
+				assert.Nil(t, x.SetFinal("io.file.buffer.size", true))
+			},
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<?xml-stylesheet type="text/xsl" href="configuration.xsl"?>
+<!--
+  Licensed under the Apache License, Version 2.0.
+-->
+<configuration>
+  <!-- namenode -->
+  <property>
+    <name>fs.defaultFS</name>
+    <value>hdfs://nn:8020</value>   <!-- keep me -->
+    <description>The default file system &amp; URI.</description>
+  </property>
+
+  <property><name>dfs.replication</name><value>3</value></property>
+  <property>
+    <name>hadoop.tmp.dir</name>
+    <value>/tmp</value>
+  </property>
+  <property>
+    <name>io.file.buffer.size</name>
+    <value>65536</value>
+    <final>true</final>
+  </property>
+</configuration>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewXmlConfig()
+			x.SetPreserveFormat(true)
+			assert.Nil(t, x.ParseXmlData([]byte(editCase)))
+			tt.modify(x)
+			data, err := x.BuildXmlData()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestXmlConfig_BuildXmlData_PreserveFormatEmpty(t *testing.T) {
+	x := NewXmlConfig()
+	x.SetPreserveFormat(true)
+	assert.Nil(t, x.ParseXmlData([]byte("<!-- empty -->\n<configuration></configuration>\n")))
+	x.SetString("a", "1")
+	data, err := x.BuildXmlData()
+	assert.Nil(t, err)
+	assert.Equal(t, "<!-- empty -->\n<configuration>\n    <property>\n        <name>a</name>\n        <value>1</value>\n    </property>\n</configuration>\n", string(data))
+}
+
+func TestXmlConfig_BuildXmlData_PreserveFormatMultiple(t *testing.T) {
+	tests := []struct {
+		name    string
+		load    func(x *XmlConfig) error
+		wantErr error
+	}{
+		{
+			name: "单个资源",
+			load: func(x *XmlConfig) error {
+				return x.AddResourceData("core-site.xml", []byte(coreSite))
+			},
+		},
+		{
+			name: "多个资源",
+			load: func(x *XmlConfig) error {
+				if err := x.AddResourceData("core-default.xml", []byte(coreDefault)); err != nil {
+					return err
+				}
+				return x.AddResourceData("core-site.xml", []byte(coreSite))
+			},
+			wantErr: ErrMultipleDocuments,
+		},
+		{
+			name: "目录中有多个文件",
+			load: func(x *XmlConfig) error {
+				dir := t.TempDir()
+				writeTempXml(t, dir, "core-default.xml", coreDefault)
+				writeTempXml(t, dir, "core-site.xml", coreSite)
+				return x.AddSource(DirSource(dir, "*.xml"), 0)
+			},
+			wantErr: ErrMultipleDocuments,
+		},
+		{
+			name: "资源之后解析xml数据",
+			load: func(x *XmlConfig) error {
+				if err := x.AddResourceData("core-default.xml", []byte(coreDefault)); err != nil {
+					return err
+				}
+				return x.ParseXmlData([]byte(coreSite))
+			},
+			wantErr: ErrMultipleDocuments,
+		},
+		{
+			name: "Reload之后只计算资源栈中的文档",
+			load: func(x *XmlConfig) error {
+				if err := x.ParseXmlData([]byte(coreSite)); err != nil {
+					return err
+				}
+				if err := x.AddResourceData("core-default.xml", []byte(coreDefault)); err != nil {
+					return err
+				}
+				return x.Reload()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewXmlConfig()
+			x.SetPreserveFormat(true)
+			assert.Nil(t, tt.load(x))
+			_, err := x.BuildXmlData()
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
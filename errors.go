@@ -0,0 +1,90 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError xml配置解析错误, 可通过errors.As获取出错的位置
+type ParseError struct {
+	// Source 出错的资源名称, 未命名的资源为空字符串
+	Source string
+	// Line 出错的行号, 从1开始
+	Line int
+	// Column 出错的列号, 从1开始按字节计算
+	Column int
+	// Property 出错的配置项名称, 未知时为空字符串
+	Property string
+	Err      error
+}
+
+// Error 实现error接口, 格式为source:line:column: property name: err
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.Source != "" {
+		b.WriteString(e.Source)
+		b.WriteString(":")
+	}
+	fmt.Fprintf(&b, "%d:%d: ", e.Line, e.Column)
+	if e.Property != "" {
+		fmt.Fprintf(&b, "property %s: ", e.Property)
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+// Unwrap 返回底层错误
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ValueError 配置值无法展开或无法转换为目标类型, 可通过errors.As获取出错的key和来源
+type ValueError struct {
+	Key   string
+	Value string
+	// Source 当前值的来源, 未知时为空字符串
+	Source string
+	Err    error
+}
+
+// Error 实现error接口
+func (e *ValueError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("key %s: invalid value %q: %v", e.Key, e.Value, e.Err)
+	}
+	return fmt.Sprintf("key %s (from %s): invalid value %q: %v", e.Key, e.Source, e.Value, e.Err)
+}
+
+// Unwrap 返回底层错误
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// valueError 为获取key时的错误附加key、值和来源, err为nil时返回nil
+func (x *XmlConfig) valueError(key, value string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ValueError{Key: key, Value: value, Source: x.currentSource(x.actualKey(key)), Err: err}
+}
@@ -0,0 +1,175 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want ParseError
+	}{
+		{
+			name: "配置项中的语法错误",
+			data: "<configuration>\n" +
+				"  <property>\n" +
+				"    <name>a</name>\n" +
+				"    <value>1</value>\n" +
+				"  </property>\n" +
+				"  <property>\n" +
+				"    <name>b</name>\n" +
+				"    <value>2</valu>\n" +
+				"  </property>\n" +
+				"</configuration>",
+			want: ParseError{Source: "core-site.xml", Line: 8, Column: 20, Property: "b"},
+		},
+		{
+			name: "错误的根元素",
+			data: "<?xml version=\"1.0\"?>\n<properties></properties>",
+			want: ParseError{Source: "core-site.xml", Line: 2, Column: 1},
+		},
+		{
+			name: "未结束的文档",
+			data: "<configuration>\n  <property><name>a</name></property>\n",
+			want: ParseError{Source: "core-site.xml", Line: 3, Column: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewXmlConfig()
+			err := x.AddResourceData("core-site.xml", []byte(tt.data))
+			var pe *ParseError
+			if !assert.True(t, errors.As(err, &pe), "%v", err) {
+				return
+			}
+			assert.Equal(t, tt.want.Source, pe.Source)
+			assert.Equal(t, tt.want.Line, pe.Line)
+			assert.Equal(t, tt.want.Column, pe.Column)
+			assert.Equal(t, tt.want.Property, pe.Property)
+			assert.True(t, strings.HasPrefix(err.Error(), "core-site.xml:"), err.Error())
+		})
+	}
+
+	dir := t.TempDir()
+	writeTempXml(t, dir, "hdfs-site.xml", "<configuration>\n<property><name>a</name><value>1</property>\n</configuration>")
+	for _, src := range []Source{FileSource(dir + "/hdfs-site.xml"), DirSource(dir, "")} {
+		_, err := src.Load()
+		var pe *ParseError
+		assert.True(t, errors.As(err, &pe))
+		assert.Equal(t, dir+"/hdfs-site.xml", pe.Source)
+		assert.Equal(t, 2, pe.Line)
+		assert.Equal(t, "a", pe.Property)
+	}
+}
+
+func TestValueError(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(`<configuration>
+    <property><name>int</name><value>abc</value></property>
+    <property><name>duration</name><value>5x</value></property>
+    <property><name>cycle</name><value>${cycle}</value></property>
+</configuration>`)))
+	x.SetString("bytes", "1y")
+
+	tests := []struct {
+		name    string
+		get     func() error
+		key     string
+		value   string
+		source  string
+		wantErr error
+	}{
+		{
+			name:    "GetInt",
+			get:     func() error { _, err := x.GetInt("int", 0); return err },
+			key:     "int",
+			value:   "abc",
+			source:  "core-site.xml",
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name:    "GetUint8",
+			get:     func() error { _, err := x.GetUint8("int", 0); return err },
+			key:     "int",
+			value:   "abc",
+			source:  "core-site.xml",
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name:    "GetDuration",
+			get:     func() error { _, err := x.GetDuration("duration", 0, time.Second); return err },
+			key:     "duration",
+			value:   "5x",
+			source:  "core-site.xml",
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name:    "GetBytes",
+			get:     func() error { _, err := x.GetBytes("bytes", 0); return err },
+			key:     "bytes",
+			value:   "1y",
+			source:  SourceProgrammatic,
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name:    "默认值",
+			get:     func() error { _, err := x.GetStorageSize("not.exist", "1y", MB); return err },
+			key:     "not.exist",
+			value:   "1y",
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name:    "展开失败",
+			get:     func() error { _, err := x.Get("cycle"); return err },
+			key:     "cycle",
+			value:   "${cycle}",
+			source:  "core-site.xml",
+			wantErr: ErrExpandCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.get()
+			var ve *ValueError
+			if !assert.True(t, errors.As(err, &ve), "%v", err) {
+				return
+			}
+			assert.Equal(t, tt.key, ve.Key)
+			assert.Equal(t, tt.value, ve.Value)
+			assert.Equal(t, tt.source, ve.Source)
+			assert.True(t, errors.Is(err, tt.wantErr))
+			assert.Contains(t, err.Error(), tt.key)
+			assert.Contains(t, err.Error(), tt.source)
+		})
+	}
+	_, err := x.GetInt("not.exist", 1)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,157 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultMaxExpandDepth 默认的变量展开最大嵌套深度, 与hadoop的MAX_SUBST一致
+const defaultMaxExpandDepth = 20
+
+var (
+	// ErrExpandCycle 变量引用存在循环
+	ErrExpandCycle = errors.New("variable expansion cycle")
+	// ErrExpandDepth 变量引用嵌套过深
+	ErrExpandDepth = errors.New("variable expansion depth too large")
+)
+
+// SetMaxExpandDepth 设置变量展开的最大嵌套深度, depth<=0时使用默认值20
+func (x *XmlConfig) SetMaxExpandDepth(depth int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.maxExpandDepth = depth
+}
+
+func (x *XmlConfig) expandDepth() int {
+	if x.maxExpandDepth <= 0 {
+		return defaultMaxExpandDepth
+	}
+	return x.maxExpandDepth
+}
+
+// expandKey 展开key对应配置值中的${...}引用
+func (x *XmlConfig) expandKey(key string) (string, bool, error) {
+	key = x.actualKey(key)
+	p, ok := x.configurations[key]
+	if !ok {
+		return "", false, nil
+	}
+	value, err := x.expand(p.Value, []string{key})
+	return value, true, err
+}
+
+// expand 展开s中的${key}、${env.VAR}、${env.VAR:-default}和${env.VAR-default}引用,
+// 无法解析的引用原样保留. 出错时返回原始的s
+func (x *XmlConfig) expand(s string, stack []string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	rest := s
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			break
+		}
+		end := matchBrace(rest, start+2)
+		if end < 0 {
+			break
+		}
+		b.WriteString(rest[:start])
+		v, ok, err := x.resolve(rest[start+2:end], stack)
+		if err != nil {
+			return s, err
+		}
+		if ok {
+			b.WriteString(v)
+		} else {
+			b.WriteString(rest[start : end+1])
+		}
+		rest = rest[end+1:]
+	}
+	b.WriteString(rest)
+	return b.String(), nil
+}
+
+// resolve 解析单个引用, 第二个返回值表示引用是否可以被解析
+func (x *XmlConfig) resolve(ref string, stack []string) (string, bool, error) {
+	if strings.HasPrefix(ref, "env.") {
+		return x.resolveEnv(ref[len("env."):], stack)
+	}
+	ref = x.actualKey(ref)
+	p, ok := x.configurations[ref]
+	if !ok {
+		return "", false, nil
+	}
+	for _, k := range stack {
+		if k == ref {
+			return "", false, fmt.Errorf("%w: %s -> %s", ErrExpandCycle, strings.Join(stack, " -> "), ref)
+		}
+	}
+	if len(stack) >= x.expandDepth() {
+		return "", false, fmt.Errorf("%w: %s -> %s", ErrExpandDepth, strings.Join(stack, " -> "), ref)
+	}
+	next := make([]string, len(stack), len(stack)+1)
+	copy(next, stack)
+	v, err := x.expand(p.Value, append(next, ref))
+	return v, err == nil, err
+}
+
+// resolveEnv 解析环境变量引用, ":-"在变量未设置或为空时使用默认值, "-"仅在变量未设置时使用默认值
+func (x *XmlConfig) resolveEnv(ref string, stack []string) (string, bool, error) {
+	name, def, hasDef, emptyAsUnset := ref, "", false, false
+	if i := strings.Index(ref, ":-"); i >= 0 {
+		name, def, hasDef, emptyAsUnset = ref[:i], ref[i+2:], true, true
+	} else if i := strings.IndexByte(ref, '-'); i >= 0 {
+		name, def, hasDef = ref[:i], ref[i+1:], true
+	}
+	if v, ok := os.LookupEnv(name); ok && !(emptyAsUnset && v == "") {
+		return v, true, nil
+	}
+	if !hasDef {
+		return "", false, nil
+	}
+	v, err := x.expand(def, stack)
+	return v, err == nil, err
+}
+
+// matchBrace 返回与s[from-2:from]处"${"匹配的'}'位置, 不存在时返回-1
+func matchBrace(s string, from int) int {
+	depth := 1
+	for i := from; i < len(s); i++ {
+		switch {
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		}
+	}
+	return -1
+}
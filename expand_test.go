@@ -0,0 +1,139 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"encoding/xml"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newExpandCase(kvs ...string) map[string]*property {
+	configurations := make(map[string]*property)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		configurations[kvs[i]] = &property{
+			XMLName: xml.Name{Local: "property"},
+			Name:    kvs[i],
+			Value:   kvs[i+1],
+		}
+	}
+	return configurations
+}
+
+func TestXmlConfig_Expand(t *testing.T) {
+	os.Setenv("XMLCONFIG_TEST_HOME", "/opt/hadoop")
+	os.Setenv("XMLCONFIG_TEST_EMPTY", "")
+	defer os.Unsetenv("XMLCONFIG_TEST_HOME")
+	defer os.Unsetenv("XMLCONFIG_TEST_EMPTY")
+	tests := []struct {
+		name           string
+		configurations map[string]*property
+		key            string
+		want           string
+		wantErr        error
+	}{
+		{
+			name: "引用其他key",
+			configurations: newExpandCase("hadoop.tmp.dir", "/tmp/hadoop-${user.name}", "user.name", "hdfs",
+				"dfs.namenode.name.dir", "${hadoop.tmp.dir}/dfs/name"),
+			key:  "dfs.namenode.name.dir",
+			want: "/tmp/hadoop-hdfs/dfs/name",
+		},
+		{
+			name:           "引用不存在的key",
+			configurations: newExpandCase("a", "${b}/x"),
+			key:            "a",
+			want:           "${b}/x",
+		},
+		{
+			name:           "环境变量",
+			configurations: newExpandCase("a", "${env.XMLCONFIG_TEST_HOME}/etc"),
+			key:            "a",
+			want:           "/opt/hadoop/etc",
+		},
+		{
+			name:           "环境变量默认值",
+			configurations: newExpandCase("a", "${env.XMLCONFIG_TEST_EMPTY:-/default}", "b", "${env.XMLCONFIG_TEST_EMPTY-/default}"),
+			key:            "a",
+			want:           "/default",
+		},
+		{
+			name:           "环境变量为空且不使用默认值",
+			configurations: newExpandCase("b", "[${env.XMLCONFIG_TEST_EMPTY-/default}]"),
+			key:            "b",
+			want:           "[]",
+		},
+		{
+			name:           "默认值中的引用",
+			configurations: newExpandCase("a", "${env.XMLCONFIG_TEST_UNSET:-${b}}", "b", "value"),
+			key:            "a",
+			want:           "value",
+		},
+		{
+			name:           "循环引用",
+			configurations: newExpandCase("a", "${b}", "b", "${a}"),
+			key:            "a",
+			want:           "${b}",
+			wantErr:        ErrExpandCycle,
+		},
+		{
+			name:           "嵌套过深",
+			configurations: newExpandCase("k0", "${k1}", "k1", "${k2}", "k2", "${k3}", "k3", "end"),
+			key:            "k0",
+			want:           "${k1}",
+			wantErr:        ErrExpandDepth,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &XmlConfig{
+				configurations: tt.configurations,
+				maxExpandDepth: 3,
+			}
+			value, err := x.Get(tt.key)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "Get(%v): %v", tt.key, err)
+			} else {
+				assert.Nil(t, err, "Get(%v)", tt.key)
+			}
+			assert.Equalf(t, tt.want, value, "Get(%v)", tt.key)
+			assert.Equalf(t, tt.want, x.GetString(tt.key, ""), "GetString(%v)", tt.key)
+		})
+	}
+}
+
+func TestXmlConfig_GetRaw(t *testing.T) {
+	x := &XmlConfig{
+		configurations: newExpandCase("a", "${b}0", "b", "1"),
+	}
+	raw, err := x.GetRaw("a")
+	assert.Nil(t, err)
+	assert.Equal(t, "${b}0", raw)
+	i, err := x.GetInt("a", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, i)
+	_, err = x.GetRaw("c")
+	assert.NotNil(t, err)
+}
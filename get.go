@@ -23,41 +23,64 @@ package xmlconfig
 
 import (
 	"errors"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // GetInt TODO
 func (x *XmlConfig) GetInt(key string, defaultInt int) (int, error) {
-	if value, ok := x.configurations[key]; ok {
-		return strconv.Atoi(value.Value)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.Atoi(value)
+		return i, x.valueError(key, value, err)
 	}
 	return defaultInt, nil
 }
 
 // GetInt8 TODO
 func (x *XmlConfig) GetInt8(key string, defaultInt8 int8) (int8, error) {
-	if value, ok := x.configurations[key]; ok {
-		i, err := strconv.ParseInt(value.Value, 10, 8)
-		return int8(i), err
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.ParseInt(value, 10, 8)
+		return int8(i), x.valueError(key, value, err)
 	}
 	return defaultInt8, nil
 }
 
 // GetInt16 TODO
 func (x *XmlConfig) GetInt16(key string, defaultInt16 int16) (int16, error) {
-	if value, ok := x.configurations[key]; ok {
-		i, err := strconv.ParseInt(value.Value, 10, 16)
-		return int16(i), err
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.ParseInt(value, 10, 16)
+		return int16(i), x.valueError(key, value, err)
 	}
 	return defaultInt16, nil
 }
 
 // GetInt32 TODO
 func (x *XmlConfig) GetInt32(key string, defaultInt32 int32) (int32, error) {
-	if value, ok := x.configurations[key]; ok {
-		i, err := strconv.ParseInt(value.Value, 10, 32)
-		return int32(i), err
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.ParseInt(value, 10, 32)
+		return int32(i), x.valueError(key, value, err)
 	}
 	return defaultInt32, nil
 
@@ -65,62 +88,142 @@ func (x *XmlConfig) GetInt32(key string, defaultInt32 int32) (int32, error) {
 
 // GetInt64 TODO
 func (x *XmlConfig) GetInt64(key string, defaultInt64 int64) (int64, error) {
-	if value, ok := x.configurations[key]; ok {
-		i, err := strconv.ParseInt(value.Value, 10, 64)
-		return i, err
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.ParseInt(value, 10, 64)
+		return i, x.valueError(key, value, err)
 	}
 	return defaultInt64, nil
 }
 
 // GetUint TODO
 func (x *XmlConfig) GetUint(key string, defaultUint uint) (uint, error) {
-	if value, ok := x.configurations[key]; ok {
-		i, err := strconv.ParseUint(value.Value, 10, 32)
-		return uint(i), err
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.ParseUint(value, 10, 32)
+		return uint(i), x.valueError(key, value, err)
 	}
 	return defaultUint, nil
 }
 
 // GetUint8 TODO
 func (x *XmlConfig) GetUint8(key string, defaultUint8 uint8) (uint8, error) {
-	if value, ok := x.configurations[key]; ok {
-		i, err := strconv.ParseUint(value.Value, 10, 8)
-		return uint8(i), err
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.ParseUint(value, 10, 8)
+		return uint8(i), x.valueError(key, value, err)
 	}
 	return defaultUint8, nil
 }
 
 // GetUint16 TODO
 func (x *XmlConfig) GetUint16(key string, defaultUint16 uint16) (uint16, error) {
-	if value, ok := x.configurations[key]; ok {
-		i, err := strconv.ParseUint(value.Value, 10, 16)
-		return uint16(i), err
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.ParseUint(value, 10, 16)
+		return uint16(i), x.valueError(key, value, err)
 	}
 	return defaultUint16, nil
 }
 
 // GetUint32 TODO
 func (x *XmlConfig) GetUint32(key string, defaultUint32 uint32) (uint32, error) {
-	if value, ok := x.configurations[key]; ok {
-		i, err := strconv.ParseUint(value.Value, 10, 32)
-		return uint32(i), err
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.ParseUint(value, 10, 32)
+		return uint32(i), x.valueError(key, value, err)
 	}
 	return defaultUint32, nil
 }
 
 // GetUint64 TODO
 func (x *XmlConfig) GetUint64(key string, defaultUint64 uint64) (uint64, error) {
-	if value, ok := x.configurations[key]; ok {
-		i, err := strconv.ParseUint(value.Value, 10, 64)
-		return i, err
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		i, err := strconv.ParseUint(value, 10, 64)
+		return i, x.valueError(key, value, err)
 	}
 	return defaultUint64, nil
 }
 
+// GetDuration 获取时间配置, 支持ns、us、ms、s、m、h、d后缀(如30s、5m、200ms、2d),
+// 没有后缀的数字以defaultUnit为单位
+func (x *XmlConfig) GetDuration(key string, defaultDuration time.Duration, defaultUnit time.Duration) (time.Duration, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		d, err := parseDuration(value, defaultUnit)
+		return d, x.valueError(key, value, err)
+	}
+	return defaultDuration, nil
+}
+
+// GetStorageSize 获取容量配置并换算为unit单位, 支持k、m、g、t、p、e后缀及kb、kib等形式(不区分大小写,
+// 均按1024进制换算)和1.5g这样的小数, 没有后缀的数字以unit为单位. key不存在时解析defaultValue
+func (x *XmlConfig) GetStorageSize(key string, defaultValue string, unit StorageUnit) (float64, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	value, ok, err := x.expandKey(key)
+	if err != nil {
+		return 0, x.valueError(key, value, err)
+	}
+	if !ok {
+		value = defaultValue
+	}
+	bytes, err := parseStorageSize(value, unit)
+	if err != nil {
+		return 0, x.valueError(key, value, err)
+	}
+	return bytes / float64(unit), nil
+}
+
+// GetBytes 获取容量配置的字节数, 如128m返回134217728, 没有后缀的数字以字节为单位
+func (x *XmlConfig) GetBytes(key string, defaultBytes int64) (int64, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		if err != nil {
+			return 0, x.valueError(key, value, err)
+		}
+		b, err := parseBytes(value, Byte)
+		return b, x.valueError(key, value, err)
+	}
+	return defaultBytes, nil
+}
+
 // GetBool TODO
 func (x *XmlConfig) GetBool(key string, defaultBool bool) bool {
-	if value, ok := x.configurations[key]; ok {
-		return strings.ToLower(strings.TrimSpace(value.Value)) == "true"
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, _ := x.expandKey(key); ok {
+		return strings.ToLower(strings.TrimSpace(value)) == "true"
 	} else {
 		return defaultBool
 	}
@@ -128,7 +231,19 @@ func (x *XmlConfig) GetBool(key string, defaultBool bool) bool {
 
 // Get TODO
 func (x *XmlConfig) Get(key string) (string, error) {
-	if value, ok := x.configurations[key]; ok {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, err := x.expandKey(key); ok {
+		return value, x.valueError(key, value, err)
+	}
+	return "", errors.New("not exist key: " + key)
+}
+
+// GetRaw 获取未展开变量引用的原始配置值
+func (x *XmlConfig) GetRaw(key string) (string, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok := x.configurations[x.actualKey(key)]; ok {
 		return value.Value, nil
 	}
 	return "", errors.New("not exist key: " + key)
@@ -136,8 +251,10 @@ func (x *XmlConfig) Get(key string) (string, error) {
 
 // GetString TODO
 func (x *XmlConfig) GetString(key string, defaultString string) string {
-	if value, ok := x.configurations[key]; ok {
-		return value.Value
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, _ := x.expandKey(key); ok {
+		return value
 	} else {
 		return defaultString
 	}
@@ -145,13 +262,25 @@ func (x *XmlConfig) GetString(key string, defaultString string) string {
 
 // GetTrimmedString TODO
 func (x *XmlConfig) GetTrimmedString(key string, defaultString string) string {
-	if value, ok := x.configurations[key]; ok {
-		return strings.TrimSpace(value.Value)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok, _ := x.expandKey(key); ok {
+		return strings.TrimSpace(value)
 	} else {
 		return strings.TrimSpace(defaultString)
 	}
 }
 
+// IsFinal 判断key是否被标记为final
+func (x *XmlConfig) IsFinal(key string) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if value, ok := x.configurations[x.actualKey(key)]; ok {
+		return value.Final
+	}
+	return false
+}
+
 // GetStrings TODO
 func (x *XmlConfig) GetStrings(key, sep string) []string {
 	if len(sep) == 0 {
@@ -183,20 +312,90 @@ func (x *XmlConfig) GetTrimmedStrings(key, sep string) []string {
 
 // GetPropsWithPrefix TODO
 func (x *XmlConfig) GetPropsWithPrefix(prefix string) map[string]string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
 	props := make(map[string]string)
-	for key, value := range x.configurations {
+	for key := range x.configurations {
 		if strings.HasPrefix(key, prefix) {
-			props[key] = value.Value
+			props[key], _, _ = x.expandKey(key)
 		}
 	}
 	return props
 }
 
+// splitTags 分割逗号分隔的tag并去除空白
+func splitTags(tag string) []string {
+	var tags []string
+	for _, t := range strings.Split(tag, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// hasTag 判断property是否带有tag, 不区分大小写
+func (p *property) hasTag(tag string) bool {
+	for _, t := range splitTags(p.Tag) {
+		if strings.EqualFold(t, strings.TrimSpace(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPropsByTag 获取带有tag的所有配置, tag不区分大小写
+func (x *XmlConfig) GetPropsByTag(tag string) map[string]string {
+	return x.GetAllPropertiesByTags([]string{tag})
+}
+
+// GetAllPropertiesByTags 获取带有tags中任一tag的所有配置
+func (x *XmlConfig) GetAllPropertiesByTags(tags []string) map[string]string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	props := make(map[string]string)
+	for key, p := range x.configurations {
+		for _, tag := range tags {
+			if p.hasTag(tag) {
+				props[key], _, _ = x.expandKey(key)
+				break
+			}
+		}
+	}
+	return props
+}
+
+// GetTags 获取key的tag列表
+func (x *XmlConfig) GetTags(key string) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if p, ok := x.configurations[x.actualKey(key)]; ok {
+		return splitTags(p.Tag)
+	}
+	return nil
+}
+
+// GetAllTags 获取所有配置中出现过的tag, 按名称排序. tag不区分大小写, 大小写不同的tag只保留按配置顺序第一次出现的写法
+func (x *XmlConfig) GetAllTags() []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	seen := make(map[string]bool)
+	tags := make([]string, 0)
+	for _, key := range x.orderedKeys() {
+		for _, t := range splitTags(x.configurations[key].Tag) {
+			if lower := strings.ToLower(t); !seen[lower] {
+				seen[lower] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
 // GetConfigKeys TODO
 func (x *XmlConfig) GetConfigKeys() []string {
-	var keys []string
-	for k, _ := range x.configurations {
-		keys = append(keys, k)
-	}
-	return keys
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.orderedKeys()
 }
@@ -25,6 +25,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -882,3 +883,183 @@ func TestXmlConfig_GetStrings(t *testing.T) {
 		})
 	}
 }
+
+func TestXmlConfig_GetDuration(t *testing.T) {
+	type args struct {
+		key             string
+		defaultDuration time.Duration
+		defaultUnit     time.Duration
+	}
+	tests := []struct {
+		name    string
+		value   string
+		args    args
+		want    time.Duration
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "带单位",
+			value:   "30s",
+			args:    args{"name1", time.Minute, time.Millisecond},
+			want:    30 * time.Second,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "默认单位",
+			value:   "3",
+			args:    args{"name1", time.Minute, time.Second},
+			want:    3 * time.Second,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "key不存在",
+			value:   "3",
+			args:    args{"name2", time.Minute, time.Second},
+			want:    time.Minute,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "value异常",
+			value:   "3x",
+			args:    args{"name1", time.Minute, time.Second},
+			want:    0,
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &XmlConfig{
+				configurations: newCase(tt.value),
+			}
+			got, err := x.GetDuration(tt.args.key, tt.args.defaultDuration, tt.args.defaultUnit)
+			if !tt.wantErr(t, err, fmt.Sprintf("GetDuration(%v)", tt.args.key)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "GetDuration(%v)", tt.args.key)
+		})
+	}
+}
+
+func TestXmlConfig_GetStorageSize(t *testing.T) {
+	type args struct {
+		key          string
+		defaultValue string
+		unit         StorageUnit
+	}
+	tests := []struct {
+		name    string
+		value   string
+		args    args
+		want    float64
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "换算为MB",
+			value:   "1.5g",
+			args:    args{"name1", "1m", MB},
+			want:    1536,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "换算为十进制KB",
+			value:   "2k",
+			args:    args{"name1", "1m", DecimalKB},
+			want:    2.048,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "没有后缀时使用目标单位",
+			value:   "128",
+			args:    args{"name1", "1m", MB},
+			want:    128,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "key不存在",
+			value:   "1g",
+			args:    args{"name2", "64k", KB},
+			want:    64,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "value异常",
+			value:   "1gg",
+			args:    args{"name1", "64k", KB},
+			want:    0,
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &XmlConfig{
+				configurations: newCase(tt.value),
+			}
+			got, err := x.GetStorageSize(tt.args.key, tt.args.defaultValue, tt.args.unit)
+			if !tt.wantErr(t, err, fmt.Sprintf("GetStorageSize(%v)", tt.args.key)) {
+				return
+			}
+			assert.InDeltaf(t, tt.want, got, 1e-9, "GetStorageSize(%v)", tt.args.key)
+		})
+	}
+}
+
+func TestXmlConfig_GetBytes(t *testing.T) {
+	x := &XmlConfig{
+		configurations: newCase("128m"),
+	}
+	n, err := x.GetBytes("name1", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(134217728), n)
+	n, err = x.GetBytes("name2", 1024)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1024), n)
+	x.SetString("name1", "16e")
+	_, err = x.GetBytes("name1", 0)
+	assert.NotNil(t, err)
+}
+
+func TestXmlConfig_GetPropsByTag(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.ParseXmlData([]byte(`<configuration>
+    <property><name>dfs.replication</name><value>3</value><tag>HDFS, REQUIRED</tag></property>
+    <property><name>dfs.blocksize</name><value>128m</value><tag>HDFS,PERFORMANCE</tag></property>
+    <property><name>yarn.resourcemanager.hostname</name><value>rm</value><tag>YARN</tag></property>
+    <property><name>hadoop.tmp.dir</name><value>/tmp</value></property>
+</configuration>`)))
+	tests := []struct {
+		name string
+		tags []string
+		want map[string]string
+	}{
+		{
+			name: "单个tag",
+			tags: []string{"HDFS"},
+			want: map[string]string{"dfs.replication": "3", "dfs.blocksize": "128m"},
+		},
+		{
+			name: "去除空白且不区分大小写",
+			tags: []string{" required "},
+			want: map[string]string{"dfs.replication": "3"},
+		},
+		{
+			name: "多个tag",
+			tags: []string{"PERFORMANCE", "YARN"},
+			want: map[string]string{"dfs.blocksize": "128m", "yarn.resourcemanager.hostname": "rm"},
+		},
+		{
+			name: "tag不存在",
+			tags: []string{"SECURITY"},
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, x.GetAllPropertiesByTags(tt.tags), "GetAllPropertiesByTags(%v)", tt.tags)
+			if len(tt.tags) == 1 {
+				assert.Equalf(t, tt.want, x.GetPropsByTag(tt.tags[0]), "GetPropsByTag(%v)", tt.tags[0])
+			}
+		})
+	}
+	assert.Equal(t, []string{"HDFS", "REQUIRED"}, x.GetTags("dfs.replication"))
+	assert.Equal(t, []string{"HDFS", "PERFORMANCE", "REQUIRED", "YARN"}, x.GetAllTags())
+}
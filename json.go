@@ -0,0 +1,123 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// unknownResource Hadoop中来源未知的配置项的resource
+const unknownResource = "Unknown"
+
+// jsonProperty Hadoop的dumpConfiguration输出中的一个配置项
+type jsonProperty struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	IsFinal  bool   `json:"isFinal"`
+	Resource string `json:"resource"`
+}
+
+// jsonDump Hadoop的dumpConfiguration输出, 导出全部配置时使用Properties, 导出单个key时使用Property
+type jsonDump struct {
+	Properties []jsonProperty `json:"properties,omitempty"`
+	Property   *jsonProperty  `json:"property,omitempty"`
+}
+
+// jsonProperty 生成key的导出内容, 值为展开变量后的值
+func (x *XmlConfig) jsonProperty(key string) jsonProperty {
+	p := x.toProperty(key)
+	resource := p.Source
+	if resource == "" {
+		resource = unknownResource
+	}
+	return jsonProperty{Key: p.Name, Value: p.Value, IsFinal: p.Final, Resource: resource}
+}
+
+// WriteJson 以Hadoop的/conf?format=json格式输出所有配置, 如
+// {"properties":[{"key":"fs.defaultFS","value":"hdfs://nn:8020","isFinal":false,"resource":"core-site.xml"}]}
+func (x *XmlConfig) WriteJson(w io.Writer) error {
+	x.mu.RLock()
+	keys := x.orderedKeys()
+	dump := jsonDump{Properties: make([]jsonProperty, 0, len(keys))}
+	for _, key := range keys {
+		dump.Properties = append(dump.Properties, x.jsonProperty(key))
+	}
+	x.mu.RUnlock()
+	return json.NewEncoder(w).Encode(dump)
+}
+
+// WriteJsonProperty 以Hadoop的/conf?format=json&name=key格式输出单个配置, 如
+// {"property":{"key":"fs.defaultFS","value":"hdfs://nn:8020","isFinal":false,"resource":"core-site.xml"}}
+func (x *XmlConfig) WriteJsonProperty(w io.Writer, key string) error {
+	x.mu.RLock()
+	key = x.actualKey(key)
+	if _, ok := x.configurations[key]; !ok {
+		x.mu.RUnlock()
+		return errors.New("not exist key: " + key)
+	}
+	p := x.jsonProperty(key)
+	x.mu.RUnlock()
+	return json.NewEncoder(w).Encode(jsonDump{Property: &p})
+}
+
+// ParseJsonProperties 解析WriteJson或WriteJsonProperty格式的json, 配置项的来源为其resource
+func ParseJsonProperties(data []byte) ([]Property, error) {
+	var dump jsonDump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, err
+	}
+	if dump.Property != nil {
+		dump.Properties = append(dump.Properties, *dump.Property)
+	}
+	props := make([]Property, 0, len(dump.Properties))
+	for _, p := range dump.Properties {
+		if p.Resource == unknownResource {
+			p.Resource = ""
+		}
+		props = append(props, Property{
+			Name:     p.Key,
+			Value:    p.Value,
+			RawValue: p.Value,
+			Final:    p.IsFinal,
+			Source:   p.Resource,
+		})
+	}
+	return props, nil
+}
+
+// ReadJson 读取WriteJson或WriteJsonProperty格式的json配置, 与ParseXmlData一样不加入资源栈且
+// 已被标记为final的key不会被覆盖. 各配置项的来源为其resource, 可通过GetPropertySources查看
+func (x *XmlConfig) ReadJson(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	props, err := ParseJsonProperties(data)
+	if err != nil {
+		return err
+	}
+	x.applyOnce(props, nil)
+	return nil
+}
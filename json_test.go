@@ -0,0 +1,87 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXmlConfig_WriteJson(t *testing.T) {
+	useDeprecations(t, HadoopDeprecations)
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-default.xml", []byte(coreDefault)))
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(coreSite)))
+	assert.Nil(t, x.SetFinal("fs.defaultFS", true))
+	x.SetString("hadoop.tmp.dir", "/tmp/${user}")
+	x.SetString("user", "hdfs")
+	assert.Nil(t, x.ReadXml(strings.NewReader(`<configuration><property><name>unnamed</name><value>1</value></property></configuration>`)))
+
+	var buf bytes.Buffer
+	assert.Nil(t, x.WriteJson(&buf))
+	assert.JSONEq(t, `{"properties":[
+		{"key":"fs.defaultFS","value":"hdfs://nn:8020","isFinal":true,"resource":"core-site.xml"},
+		{"key":"hadoop.tmp.dir","value":"/tmp/hdfs","isFinal":false,"resource":"programmatically"},
+		{"key":"io.file.buffer.size","value":"4096","isFinal":true,"resource":"core-default.xml"},
+		{"key":"user","value":"hdfs","isFinal":false,"resource":"programmatically"},
+		{"key":"unnamed","value":"1","isFinal":false,"resource":"Unknown"}
+	]}`, buf.String())
+
+	buf.Reset()
+	assert.Nil(t, x.WriteJsonProperty(&buf, "fs.default.name"))
+	assert.JSONEq(t, `{"property":{"key":"fs.defaultFS","value":"hdfs://nn:8020","isFinal":true,"resource":"core-site.xml"}}`, buf.String())
+	assert.NotNil(t, x.WriteJsonProperty(&buf, "not.exist"))
+}
+
+func TestXmlConfig_ReadJson(t *testing.T) {
+	useDeprecations(t, HadoopDeprecations)
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-default.xml", []byte(coreDefault)))
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(coreSite)))
+	assert.Nil(t, x.SetFinal("fs.defaultFS", true))
+	var buf bytes.Buffer
+	assert.Nil(t, x.WriteJson(&buf))
+
+	live := NewXmlConfig()
+	assert.Nil(t, live.ReadJson(&buf))
+	assert.Equal(t, x.Snapshot(), live.Snapshot())
+	assert.Equal(t, x.GetConfigKeys(), live.GetConfigKeys())
+	assert.True(t, live.IsFinal("fs.defaultFS"))
+	assert.Equal(t, []string{"core-default.xml"}, live.GetPropertySources("io.file.buffer.size"))
+	assert.True(t, Diff(x, live).Empty())
+
+	assert.Nil(t, live.ReadJson(strings.NewReader(`{"property":{"key":"fs.defaultFS","value":"hdfs://other","isFinal":false,"resource":"Unknown"}}`)))
+	assert.Equal(t, "hdfs://nn:8020", live.GetString("fs.defaultFS", ""))
+	assert.Equal(t, []string{"fs.defaultFS"}, live.SkippedFinalKeys())
+	assert.Nil(t, live.ReadJson(strings.NewReader(`{"property":{"key":"fs.default.name","value":"hdfs://old","isFinal":false,"resource":"Unknown"}}`)))
+	assert.Equal(t, "hdfs://nn:8020", live.GetString("fs.defaultFS", ""))
+	assert.Nil(t, live.Reload())
+	assert.Equal(t, x.Snapshot(), live.Snapshot())
+
+	assert.NotNil(t, live.ReadJson(strings.NewReader(`{"properties":`)))
+	props, err := ParseJsonProperties([]byte(`{"properties":[{"key":"a","value":"1","isFinal":true,"resource":"a.xml"}]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []Property{{Name: "a", Value: "1", RawValue: "1", Final: true, Source: "a.xml"}}, props)
+}
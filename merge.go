@@ -0,0 +1,183 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMergeConflict 以MergeFailOnConflict合并时存在值不同的key
+var ErrMergeConflict = errors.New("merge conflict")
+
+// MergeStrategy 合并配置时key冲突的处理方式
+type MergeStrategy int
+
+const (
+	// MergeOverride 使用合并进来的值, 与先后读取两个文件的效果相同
+	MergeOverride MergeStrategy = iota
+	// MergeKeepExisting 保留已有的值
+	MergeKeepExisting
+	// MergeFailOnConflict 存在冲突时返回ErrMergeConflict且不做任何修改
+	MergeFailOnConflict
+	// MergeAppend 将值视为GetStrings以逗号分割的列表, 追加已有值中不存在的元素
+	MergeAppend
+)
+
+// MergeConflict 合并时两个配置中值不同的key
+type MergeConflict struct {
+	Key            string
+	Existing       string // 已有的原始值
+	ExistingSource string
+	Incoming       string // 合并进来的原始值
+	IncomingSource string
+	Merged         string // 合并后的值
+	Final          bool   // 已有的key被标记为final, 保留了原值
+}
+
+// MergeReport 一次合并的结果
+type MergeReport struct {
+	Added     []string // 新增的key, 按合并顺序排列
+	Conflicts []MergeConflict
+}
+
+// String 每行输出一个冲突及其来源
+func (r *MergeReport) String() string {
+	var b strings.Builder
+	for _, c := range r.Conflicts {
+		fmt.Fprintf(&b, "%s: %q (from %s) vs %q (from %s) -> %q", c.Key,
+			c.Existing, sourceName(c.ExistingSource), c.Incoming, sourceName(c.IncomingSource), c.Merged)
+		if c.Final {
+			b.WriteString(" (final)")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// sourceName 返回用于输出的来源名称
+func sourceName(source string) string {
+	if source == "" {
+		return "unknown"
+	}
+	return source
+}
+
+// Merge 按strategy将src中的配置合并到x中, 返回新增的key和所有冲突. 被标记为final的key保留原值,
+// 以MergeFailOnConflict合并时final的key也视为冲突. 合并的值与Set设置的值一样会在Reload后再次应用,
+// 新增的key和以MergeOverride覆盖的key带有src中的tag、描述和final标记, 来源为src中的来源.
+// 合并后的值不满足SetSchema设置的schema时返回包含所有错误的*SchemaError且不做任何修改
+func (x *XmlConfig) Merge(src *XmlConfig, strategy MergeStrategy) (*MergeReport, error) {
+	incoming := src.Properties()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	report := &MergeReport{}
+	type update struct {
+		p     Property
+		value string
+	}
+	var updates []update
+	for _, in := range incoming {
+		key := x.actualKey(in.Name)
+		old, ok := x.configurations[key]
+		if !ok {
+			report.Added = append(report.Added, key)
+			updates = append(updates, update{p: in, value: in.RawValue})
+			continue
+		}
+		if old.Value == in.RawValue {
+			continue
+		}
+		c := MergeConflict{
+			Key:            key,
+			Existing:       old.Value,
+			ExistingSource: x.currentSource(key),
+			Incoming:       in.RawValue,
+			IncomingSource: in.Source,
+			Merged:         old.Value,
+			Final:          old.Final,
+		}
+		if !old.Final {
+			switch strategy {
+			case MergeOverride:
+				c.Merged = in.RawValue
+			case MergeAppend:
+				c.Merged = appendList(old.Value, in.RawValue)
+			}
+		}
+		report.Conflicts = append(report.Conflicts, c)
+		if c.Merged != old.Value {
+			updates = append(updates, update{p: in, value: c.Merged})
+		}
+	}
+	if strategy == MergeFailOnConflict && len(report.Conflicts) > 0 {
+		keys := make([]string, 0, len(report.Conflicts))
+		for _, c := range report.Conflicts {
+			keys = append(keys, c.Key)
+		}
+		return report, fmt.Errorf("%w: %s", ErrMergeConflict, strings.Join(keys, ", "))
+	}
+	var errs []*ValueError
+	for _, u := range updates {
+		if err := x.checkSchema(x.actualKey(u.p.Name), u.value, u.p.Source); err != nil {
+			errs = append(errs, err.(*ValueError))
+		}
+	}
+	if len(errs) > 0 {
+		return report, &SchemaError{Errors: errs}
+	}
+
+	for _, u := range updates {
+		key := x.actualKey(u.p.Name)
+		_, existed := x.configurations[key]
+		if err := x.set(key, u.value); err != nil {
+			continue
+		}
+		x.overlayValue(key, u.value, u.p.Source)
+		if !existed || strategy == MergeOverride {
+			p := x.configurations[key]
+			p.Tag = strings.Join(u.p.Tags, ",")
+			p.Description = u.p.Description
+			p.Final = u.p.Final
+			x.overlayMeta(key)
+		}
+		x.addSource(key, u.p.Source)
+	}
+	return report, nil
+}
+
+// appendList 将incoming中existing不包含的元素追加到existing后, 元素以逗号分隔并去除空白
+func appendList(existing, incoming string) string {
+	var items []string
+	seen := make(map[string]bool)
+	for _, s := range []string{existing, incoming} {
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" && !seen[item] {
+				seen[item] = true
+				items = append(items, item)
+			}
+		}
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,284 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// ThreeWayConflict 三方合并中一个key的冲突, Fields为冲突的字段, 取值为value、tags、description和final.
+// key在某一方被删除而另一方修改了它时Fields为修改的字段, 被删除一方的Property为nil
+type ThreeWayConflict struct {
+	Key    string
+	Fields []string
+	Base   *Property
+	Ours   *Property
+	Theirs *Property
+}
+
+// ThreeWayResult 三方合并的结果. 有冲突的key在Merged中保留ours的内容(被ours删除的key保留theirs的内容),
+// 通过ThreeWayResult的BuildXmlData或WriteXmlFile写出时附带冲突标记, 解决冲突后可调用Resolve清除标记
+type ThreeWayResult struct {
+	Merged    *XmlConfig
+	Conflicts []ThreeWayConflict
+}
+
+// HasConflicts 判断是否存在冲突
+func (r *ThreeWayResult) HasConflicts() bool {
+	return len(r.Conflicts) > 0
+}
+
+// markedProperty 附带冲突标记的配置项, 仅用于输出三方合并的结果
+type markedProperty struct {
+	XMLName     xml.Name `xml:"property"`
+	Comment     string   `xml:",comment"`
+	Name        string   `xml:"name"`
+	Value       string   `xml:"value"`
+	Tag         string   `xml:"tag"`
+	Description string   `xml:"description"`
+	Final       bool     `xml:"final,omitempty"`
+}
+
+type markedConfiguration struct {
+	XMLName    xml.Name         `xml:"configuration"`
+	Properties []markedProperty `xml:"property"`
+}
+
+// Resolve 将key的冲突标记为已解决并从Conflicts中删除, 之后BuildXmlData不再为其输出冲突标记
+func (r *ThreeWayResult) Resolve(key string) {
+	for i, c := range r.Conflicts {
+		if c.Key == key {
+			r.Conflicts = append(r.Conflicts[:i:i], r.Conflicts[i+1:]...)
+			return
+		}
+	}
+}
+
+// BuildXmlData 生成Merged的xml, Conflicts中的配置项以xml注释的形式附带git diff3格式的冲突标记
+func (r *ThreeWayResult) BuildXmlData() ([]byte, error) {
+	markers := make(map[string]string, len(r.Conflicts))
+	for _, c := range r.Conflicts {
+		markers[c.Key] = conflictComment(c.Fields, c.Base, c.Ours, c.Theirs)
+	}
+	x := r.Merged
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var properties []markedProperty
+	for _, key := range x.orderedKeys() {
+		p := x.configurations[key]
+		properties = append(properties, markedProperty{
+			Comment:     markers[key],
+			Name:        p.Name,
+			Value:       p.Value,
+			Tag:         p.Tag,
+			Description: p.Description,
+			Final:       p.Final,
+		})
+	}
+	data, err := xml.MarshalIndent(&markedConfiguration{Properties: properties}, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// WriteXmlFile 将BuildXmlData的结果写入文件
+func (r *ThreeWayResult) WriteXmlFile(xmlFilePath string) error {
+	data, err := r.BuildXmlData()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(xmlFilePath, data, 0644)
+}
+
+// threeWayFields 参与三方合并的字段及其比较方式
+var threeWayFields = []struct {
+	name  string
+	equal func(a, b Property) bool
+	apply func(dst *property, src Property)
+}{
+	{
+		name:  "value",
+		equal: func(a, b Property) bool { return a.RawValue == b.RawValue },
+		apply: func(dst *property, src Property) { dst.Value = src.RawValue },
+	},
+	{
+		name:  "tags",
+		equal: func(a, b Property) bool { return equalTags(a.Tags, b.Tags) },
+		apply: func(dst *property, src Property) { dst.Tag = strings.Join(src.Tags, ",") },
+	},
+	{
+		name:  "description",
+		equal: func(a, b Property) bool { return a.Description == b.Description },
+		apply: func(dst *property, src Property) { dst.Description = src.Description },
+	},
+	{
+		name:  "final",
+		equal: func(a, b Property) bool { return a.Final == b.Final },
+		apply: func(dst *property, src Property) { dst.Final = src.Final },
+	},
+}
+
+// ThreeWayMerge 以base为共同祖先合并ours和theirs: 只有一方修改的字段自动采用修改后的内容,
+// 双方修改且不同的字段记为冲突. 结果中key的顺序与ours相同, theirs新增的key排在最后
+func ThreeWayMerge(base, ours, theirs *XmlConfig) *ThreeWayResult {
+	bp, baseKeys := threeWayProperties(base)
+	op, keys := threeWayProperties(ours)
+	tp, theirKeys := threeWayProperties(theirs)
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		seen[key] = true
+	}
+	for _, key := range append(theirKeys, baseKeys...) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	r := &ThreeWayResult{Merged: NewXmlConfig()}
+	for _, key := range keys {
+		b, o, t := bp[key], op[key], tp[key]
+		var merged *property
+		var fields []string
+		switch {
+		case o == nil && t == nil:
+			continue
+		case o == nil || t == nil:
+			// 一方没有该key: 新增的key直接采用, 删除的key在另一方未修改时删除
+			exists := o
+			if exists == nil {
+				exists = t
+			}
+			if b == nil {
+				merged = newMergedProperty(*exists)
+			} else if fields = changedFields(*b, *exists); len(fields) > 0 {
+				merged = newMergedProperty(*exists)
+			}
+		default:
+			if b == nil {
+				// 双方都新增了该key, 以空配置项作为共同祖先
+				b = &Property{Name: key}
+			}
+			merged = newMergedProperty(*o)
+			for _, f := range threeWayFields {
+				switch {
+				case f.equal(*o, *t), f.equal(*b, *t):
+					// 双方相同或只有ours修改
+				case f.equal(*b, *o):
+					f.apply(merged, *t)
+				default:
+					fields = append(fields, f.name)
+				}
+			}
+		}
+		if len(fields) > 0 {
+			r.Conflicts = append(r.Conflicts, ThreeWayConflict{Key: key, Fields: fields, Base: bp[key], Ours: o, Theirs: t})
+		}
+		if merged != nil {
+			source := ""
+			if o != nil && merged.Value == o.RawValue {
+				source = o.Source
+			} else if t != nil {
+				source = t.Source
+			}
+			r.Merged.putProperty(*merged, source)
+		}
+	}
+	return r
+}
+
+// threeWayProperties 按顺序返回x中的所有配置项, x为nil时返回空
+func threeWayProperties(x *XmlConfig) (map[string]*Property, []string) {
+	props := make(map[string]*Property)
+	var keys []string
+	if x == nil {
+		return props, keys
+	}
+	for _, p := range x.Properties() {
+		p := p
+		props[p.Name] = &p
+		keys = append(keys, p.Name)
+	}
+	return props, keys
+}
+
+// changedFields 返回a和b之间不同的字段
+func changedFields(a, b Property) []string {
+	var fields []string
+	for _, f := range threeWayFields {
+		if !f.equal(a, b) {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// newMergedProperty 由只读视图生成配置项
+func newMergedProperty(p Property) *property {
+	return &property{
+		XMLName:     xml.Name{Local: "property"},
+		Name:        p.Name,
+		Value:       p.RawValue,
+		Tag:         strings.Join(p.Tags, ","),
+		Description: p.Description,
+		Final:       p.Final,
+	}
+}
+
+// conflictComment 生成冲突标记, 格式与git的diff3冲突标记相同, 不存在的一方输出为(deleted)
+func conflictComment(fields []string, base, ours, theirs *Property) string {
+	var b strings.Builder
+	section := func(marker string, p *Property) {
+		b.WriteString(marker + "\n")
+		if p == nil {
+			b.WriteString("(deleted)\n")
+			return
+		}
+		for _, f := range fields {
+			switch f {
+			case "value":
+				fmt.Fprintf(&b, "value: %s\n", p.RawValue)
+			case "tags":
+				fmt.Fprintf(&b, "tags: %s\n", strings.Join(p.Tags, ","))
+			case "description":
+				fmt.Fprintf(&b, "description: %s\n", p.Description)
+			case "final":
+				fmt.Fprintf(&b, "final: %t\n", p.Final)
+			}
+		}
+	}
+	b.WriteString("\n")
+	section("<<<<<<< ours", ours)
+	section("||||||| base", base)
+	section("=======", theirs)
+	b.WriteString(">>>>>>> theirs\n")
+	// xml注释中不能出现--
+	comment := b.String()
+	for strings.Contains(comment, "--") {
+		comment = strings.ReplaceAll(comment, "--", "- -")
+	}
+	return comment
+}
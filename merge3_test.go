@@ -0,0 +1,143 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newThreeWayCase(t *testing.T) (*XmlConfig, *XmlConfig, *XmlConfig) {
+	base, ours, theirs := NewXmlConfig(), NewXmlConfig(), NewXmlConfig()
+	assert.Nil(t, base.AddResourceData("base", []byte(`<configuration>
+    <property><name>dfs.replication</name><value>3</value></property>
+    <property><name>dfs.blocksize</name><value>128m</value><description>block size</description></property>
+    <property><name>dfs.permissions.enabled</name><value>true</value><tag>HDFS</tag></property>
+    <property><name>dfs.heartbeat.interval</name><value>3</value></property>
+    <property><name>dfs.webhdfs.enabled</name><value>true</value></property>
+    <property><name>dfs.namenode.handler.count</name><value>10</value></property>
+</configuration>`)))
+	assert.Nil(t, ours.AddResourceData("ours", []byte(`<configuration>
+    <property><name>dfs.replication</name><value>2</value></property>
+    <property><name>dfs.blocksize</name><value>256m</value><description>operator block size</description></property>
+    <property><name>dfs.permissions.enabled</name><value>false</value><tag>HDFS</tag></property>
+    <property><name>dfs.heartbeat.interval</name><value>3</value></property>
+    <property><name>dfs.namenode.handler.count</name><value>10</value></property>
+    <property><name>ours.only</name><value>o</value></property>
+</configuration>`)))
+	assert.Nil(t, theirs.AddResourceData("theirs", []byte(`<configuration>
+    <property><name>dfs.replication</name><value>3</value></property>
+    <property><name>dfs.blocksize</name><value>256m</value><description>upstream block size</description></property>
+    <property><name>dfs.permissions.enabled</name><value>true</value><tag>HDFS,SECURITY</tag></property>
+    <property><name>dfs.heartbeat.interval</name><value>3s</value></property>
+    <property><name>dfs.webhdfs.enabled</name><value>true</value></property>
+    <property><name>theirs.only</name><value>t</value></property>
+</configuration>`)))
+	return base, ours, theirs
+}
+
+func TestThreeWayMerge(t *testing.T) {
+	r := ThreeWayMerge(newThreeWayCase(t))
+	assert.Equal(t, map[string]string{
+		"dfs.replication":         "2",
+		"dfs.blocksize":           "256m",
+		"dfs.permissions.enabled": "false",
+		"dfs.heartbeat.interval":  "3s",
+		"ours.only":               "o",
+		"theirs.only":             "t",
+	}, r.Merged.Snapshot())
+	assert.Equal(t, []string{"dfs.replication", "dfs.blocksize", "dfs.permissions.enabled", "dfs.heartbeat.interval",
+		"ours.only", "theirs.only"}, r.Merged.GetConfigKeys())
+	assert.Equal(t, []string{"HDFS", "SECURITY"}, r.Merged.GetTags("dfs.permissions.enabled"))
+	assert.Equal(t, []string{"theirs"}, r.Merged.GetPropertySources("dfs.heartbeat.interval"))
+	assert.Equal(t, []string{"ours"}, r.Merged.GetPropertySources("dfs.replication"))
+
+	assert.True(t, r.HasConflicts())
+	if assert.Len(t, r.Conflicts, 1) {
+		c := r.Conflicts[0]
+		assert.Equal(t, "dfs.blocksize", c.Key)
+		assert.Equal(t, []string{"description"}, c.Fields)
+		assert.Equal(t, "block size", c.Base.Description)
+		assert.Equal(t, "operator block size", c.Ours.Description)
+		assert.Equal(t, "upstream block size", c.Theirs.Description)
+	}
+
+	data, err := r.Merged.BuildXmlData()
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "<!--")
+	data, err = r.BuildXmlData()
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `    <property>
+        <!--
+<<<<<<< ours
+description: operator block size
+||||||| base
+description: block size
+=======
+description: upstream block size
+>>>>>>> theirs
+-->
+        <name>dfs.blocksize</name>`)
+	assert.Equal(t, 1, strings.Count(string(data), "<!--"))
+
+	c := NewXmlConfig()
+	assert.Nil(t, c.ParseXmlData(data))
+	assert.Equal(t, r.Merged.Snapshot(), c.Snapshot())
+
+	r.Merged.SetStringWithDescription("dfs.blocksize", "256m", "operator block size")
+	r.Resolve("dfs.blocksize")
+	assert.False(t, r.HasConflicts())
+	data, err = r.BuildXmlData()
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "<!--")
+}
+
+func TestThreeWayMerge_Deleted(t *testing.T) {
+	base, ours, theirs := newThreeWayCase(t)
+	ours.unset("dfs.webhdfs.enabled")
+	theirs.SetString("dfs.webhdfs.enabled", "false")
+	theirs.unset("dfs.namenode.handler.count")
+	ours.SetString("dfs.namenode.handler.count", "--20")
+
+	r := ThreeWayMerge(base, ours, theirs)
+	assert.Len(t, r.Conflicts, 3)
+	deleted := r.Conflicts[1]
+	assert.Equal(t, "dfs.namenode.handler.count", deleted.Key)
+	assert.Nil(t, deleted.Theirs)
+	assert.Equal(t, "--20", r.Merged.GetString("dfs.namenode.handler.count", ""))
+	deleted = r.Conflicts[2]
+	assert.Equal(t, "dfs.webhdfs.enabled", deleted.Key)
+	assert.Equal(t, []string{"value"}, deleted.Fields)
+	assert.Nil(t, deleted.Ours)
+	assert.Equal(t, "false", r.Merged.GetString("dfs.webhdfs.enabled", ""))
+
+	data, err := r.BuildXmlData()
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "value: - -20\n||||||| base\nvalue: 10\n=======\n(deleted)\n>>>>>>> theirs")
+	assert.Contains(t, string(data), "<<<<<<< ours\n(deleted)\n")
+
+	r = ThreeWayMerge(base, ours, ours)
+	assert.False(t, r.HasConflicts())
+	assert.Equal(t, ours.Snapshot(), r.Merged.Snapshot())
+}
@@ -0,0 +1,173 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mergeBase = `<configuration>
+    <property><name>dfs.replication</name><value>3</value></property>
+    <property><name>dfs.datanode.data.dir</name><value>/data/1, /data/2</value></property>
+    <property><name>fs.defaultFS</name><value>hdfs://base</value><final>true</final></property>
+    <property><name>io.file.buffer.size</name><value>4096</value></property>
+</configuration>`
+
+const mergeOverlay = `<configuration>
+    <property><name>dfs.replication</name><value>2</value></property>
+    <property><name>dfs.datanode.data.dir</name><value>/data/2,/data/3</value></property>
+    <property><name>fs.defaultFS</name><value>hdfs://prod</value></property>
+    <property><name>io.file.buffer.size</name><value>4096</value></property>
+    <property><name>dfs.nameservices</name><value>prod</value><tag>HDFS</tag><description>ns</description></property>
+</configuration>`
+
+func newMergeCase(t *testing.T) (*XmlConfig, *XmlConfig) {
+	base, overlay := NewXmlConfig(), NewXmlConfig()
+	assert.Nil(t, base.AddResourceData("base.xml", []byte(mergeBase)))
+	assert.Nil(t, overlay.AddResourceData("prod.xml", []byte(mergeOverlay)))
+	return base, overlay
+}
+
+func TestXmlConfig_Merge(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy MergeStrategy
+		want     map[string]string
+		wantErr  error
+	}{
+		{
+			name:     "覆盖",
+			strategy: MergeOverride,
+			want: map[string]string{
+				"dfs.replication":       "2",
+				"dfs.datanode.data.dir": "/data/2,/data/3",
+				"fs.defaultFS":          "hdfs://base",
+				"io.file.buffer.size":   "4096",
+				"dfs.nameservices":      "prod",
+			},
+		},
+		{
+			name:     "保留已有值",
+			strategy: MergeKeepExisting,
+			want: map[string]string{
+				"dfs.replication":       "3",
+				"dfs.datanode.data.dir": "/data/1, /data/2",
+				"fs.defaultFS":          "hdfs://base",
+				"io.file.buffer.size":   "4096",
+				"dfs.nameservices":      "prod",
+			},
+		},
+		{
+			name:     "追加列表",
+			strategy: MergeAppend,
+			want: map[string]string{
+				"dfs.replication":       "3,2",
+				"dfs.datanode.data.dir": "/data/1,/data/2,/data/3",
+				"fs.defaultFS":          "hdfs://base",
+				"io.file.buffer.size":   "4096",
+				"dfs.nameservices":      "prod",
+			},
+		},
+		{
+			name:     "冲突时失败",
+			strategy: MergeFailOnConflict,
+			want: map[string]string{
+				"dfs.replication":       "3",
+				"dfs.datanode.data.dir": "/data/1, /data/2",
+				"fs.defaultFS":          "hdfs://base",
+				"io.file.buffer.size":   "4096",
+			},
+			wantErr: ErrMergeConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, overlay := newMergeCase(t)
+			report, err := base.Merge(overlay, tt.strategy)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "%v", err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.want, base.Snapshot())
+			assert.Equal(t, []string{"dfs.nameservices"}, report.Added)
+			assert.Len(t, report.Conflicts, 3)
+			assert.Equal(t, MergeConflict{
+				Key:            "fs.defaultFS",
+				Existing:       "hdfs://base",
+				ExistingSource: "base.xml",
+				Incoming:       "hdfs://prod",
+				IncomingSource: "prod.xml",
+				Merged:         "hdfs://base",
+				Final:          true,
+			}, report.Conflicts[2])
+
+			if tt.wantErr == nil {
+				assert.Nil(t, base.Reload())
+				assert.Equal(t, tt.want, base.Snapshot())
+				assert.Equal(t, []string{"HDFS"}, base.GetTags("dfs.nameservices"))
+				assert.Equal(t, []string{"prod.xml"}, base.GetPropertySources("dfs.nameservices"))
+			}
+		})
+	}
+}
+
+func TestMergeReport_String(t *testing.T) {
+	base, overlay := newMergeCase(t)
+	report, _ := base.Merge(overlay, MergeFailOnConflict)
+	assert.Equal(t, `dfs.replication: "3" (from base.xml) vs "2" (from prod.xml) -> "3"
+dfs.datanode.data.dir: "/data/1, /data/2" (from base.xml) vs "/data/2,/data/3" (from prod.xml) -> "/data/1, /data/2"
+fs.defaultFS: "hdfs://base" (from base.xml) vs "hdfs://prod" (from prod.xml) -> "hdfs://base" (final)
+`, report.String())
+}
+
+func TestXmlConfig_Merge_Schema(t *testing.T) {
+	s, err := NewSchema(KeySchema{Name: "n", Type: TypeInt, Min: "1", Max: "5"})
+	assert.Nil(t, err)
+	x := NewXmlConfig()
+	x.SetSchema(s)
+	x.SetString("n", "3")
+	src := NewXmlConfig()
+	assert.Nil(t, src.ParseXmlData([]byte(`<configuration>
+    <property><name>n</name><value>999</value></property>
+    <property><name>other</name><value>1</value></property>
+</configuration>`)))
+
+	for _, strategy := range []MergeStrategy{MergeOverride, MergeAppend} {
+		_, err = x.Merge(src, strategy)
+		var se *SchemaError
+		if assert.ErrorAs(t, err, &se) {
+			assert.Len(t, se.Errors, 1)
+			assert.Equal(t, "n", se.Errors[0].Key)
+		}
+		assert.Equal(t, "3", x.GetString("n", ""))
+		assert.Equal(t, "", x.GetString("other", ""))
+	}
+
+	report, err := x.Merge(src, MergeKeepExisting)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"other"}, report.Added)
+	assert.Equal(t, "3", x.GetString("n", ""))
+}
@@ -0,0 +1,122 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"sort"
+)
+
+// SetSortedOutput 设置BuildXmlData、String和GetConfigKeys是否按key排序输出,
+// 默认按配置在文档中的顺序输出, 新增的key追加在最后
+func (x *XmlConfig) SetSortedOutput(sorted bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.sortedOutput = sorted
+}
+
+// SetKeyOrder 调整输出顺序, keys中的key按给定顺序排在最前面, 其余key保持原有顺序
+func (x *XmlConfig) SetKeyOrder(keys ...string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	ordered := make([]string, 0, len(x.keys))
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := x.configurations[k]; ok && !seen[k] {
+			ordered = append(ordered, k)
+			seen[k] = true
+		}
+	}
+	for _, k := range x.orderedKeys() {
+		if !seen[k] {
+			ordered = append(ordered, k)
+		}
+	}
+	x.keys = ordered
+}
+
+// MoveKeyAfter 将key移动到after之后, after为空时移动到最前面
+func (x *XmlConfig) MoveKeyAfter(key, after string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.configurations[key]; !ok {
+		return errors.New("not exist key: " + key)
+	}
+	if _, ok := x.configurations[after]; after != "" && !ok {
+		return errors.New("not exist key: " + after)
+	}
+	keys := x.orderedKeys()
+	ordered := make([]string, 0, len(keys))
+	if after == "" {
+		ordered = append(ordered, key)
+	}
+	for _, k := range keys {
+		if k == key {
+			continue
+		}
+		ordered = append(ordered, k)
+		if k == after {
+			ordered = append(ordered, key)
+		}
+	}
+	x.keys = ordered
+	return nil
+}
+
+// addKey 记录新增key的顺序
+func (x *XmlConfig) addKey(key string) {
+	x.keys = append(x.keys, key)
+}
+
+// removeKey 删除key的顺序记录
+func (x *XmlConfig) removeKey(key string) {
+	for i, k := range x.keys {
+		if k == key {
+			x.keys = append(x.keys[:i], x.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// orderedKeys 返回输出顺序下的所有key, 没有顺序记录的key按名称排序追加在最后
+func (x *XmlConfig) orderedKeys() []string {
+	keys := make([]string, 0, len(x.configurations))
+	seen := make(map[string]bool, len(x.configurations))
+	for _, k := range x.keys {
+		if _, ok := x.configurations[k]; ok && !seen[k] {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+	}
+	var rest []string
+	for k := range x.configurations {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
+	if x.sortedOutput {
+		sort.Strings(keys)
+	}
+	return keys
+}
@@ -0,0 +1,125 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const orderCase = `<configuration>
+    <property><name>c</name><value>3</value></property>
+    <property><name>a</name><value>1</value></property>
+    <property><name>b</name><value>2</value></property>
+</configuration>`
+
+func buildNames(t *testing.T, x *XmlConfig) []string {
+	data, err := x.BuildXmlData()
+	assert.Nil(t, err)
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "<name>") {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(line, "<name>"), "</name>"))
+		}
+	}
+	return names
+}
+
+func TestXmlConfig_BuildXmlData_Order(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(x *XmlConfig)
+		want   []string
+	}{
+		{
+			name:   "保持文档顺序",
+			modify: func(x *XmlConfig) {},
+			want:   []string{"c", "a", "b"},
+		},
+		{
+			name: "新增key追加在最后",
+			modify: func(x *XmlConfig) {
+				x.SetString("d", "4")
+				x.SetString("a", "10")
+			},
+			want: []string{"c", "a", "b", "d"},
+		},
+		{
+			name: "删除key",
+			modify: func(x *XmlConfig) {
+				x.unset("a")
+			},
+			want: []string{"c", "b"},
+		},
+		{
+			name: "按key排序",
+			modify: func(x *XmlConfig) {
+				x.SetString("0", "0")
+				x.SetSortedOutput(true)
+			},
+			want: []string{"0", "a", "b", "c"},
+		},
+		{
+			name: "指定顺序",
+			modify: func(x *XmlConfig) {
+				x.SetKeyOrder("b", "not.exist", "a")
+			},
+			want: []string{"b", "a", "c"},
+		},
+		{
+			name: "插入到指定key之后",
+			modify: func(x *XmlConfig) {
+				x.SetString("d", "4")
+				assert.Nil(t, x.MoveKeyAfter("d", "c"))
+				assert.Nil(t, x.MoveKeyAfter("b", ""))
+				assert.NotNil(t, x.MoveKeyAfter("e", ""))
+			},
+			want: []string{"b", "c", "d", "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewXmlConfig()
+			assert.Nil(t, x.ParseXmlData([]byte(orderCase)))
+			tt.modify(x)
+			assert.Equal(t, tt.want, buildNames(t, x))
+			assert.Equal(t, tt.want, x.GetConfigKeys())
+		})
+	}
+}
+
+func TestXmlConfig_BuildXmlData_Stable(t *testing.T) {
+	x := &XmlConfig{
+		configurations: newExpandCase("c", "3", "a", "1", "b", "2"),
+	}
+	first, err := x.BuildXmlData()
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		data, err := x.BuildXmlData()
+		assert.Nil(t, err)
+		assert.Equal(t, string(first), string(data))
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, buildNames(t, x))
+}
@@ -0,0 +1,154 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// SourceCommandLine 通过-D参数设置的配置来源, 与hadoop的GenericOptionsParser一致
+const SourceCommandLine = "from command line"
+
+// ApplyArgs 解析args中"-D key=value"和"-Dkey=value"形式的参数并覆盖对应的配置, 返回其余的参数.
+// "--"之后的参数不再解析. 格式错误时不做任何修改; 被标记为final或不满足schema的key保持不变,
+// 其余的key照常设置并返回第一个错误. 设置的值在Reload之后会被再次应用, 来源为SourceCommandLine
+func (x *XmlConfig) ApplyArgs(args []string) ([]string, error) {
+	var defines [][2]string
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return x.applyDefines(defines, append(rest, args[i+1:]...))
+		case arg == "-D":
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("missing key=value after %s", arg)
+			}
+			i++
+			arg = args[i]
+		case strings.HasPrefix(arg, "-D"):
+			arg = arg[len("-D"):]
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		key, value, err := parseDefine(arg)
+		if err != nil {
+			return nil, err
+		}
+		defines = append(defines, [2]string{key, value})
+	}
+	return x.applyDefines(defines, rest)
+}
+
+func (x *XmlConfig) applyDefines(defines [][2]string, rest []string) ([]string, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var first error
+	for _, d := range defines {
+		if err := x.setValueFrom(d[0], d[1], SourceCommandLine); err != nil && first == nil {
+			first = err
+		}
+	}
+	return rest, first
+}
+
+// parseDefine 解析key=value, key不能为空
+func parseDefine(s string) (string, string, error) {
+	i := strings.IndexByte(s, '=')
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid property %q, expected key=value", s)
+	}
+	key := strings.TrimSpace(s[:i])
+	if key == "" {
+		return "", "", fmt.Errorf("invalid property %q, empty key", s)
+	}
+	return key, s[i+1:], nil
+}
+
+// defineFlag 实现flag.Value, 每次出现时覆盖一个配置
+type defineFlag struct {
+	x *XmlConfig
+}
+
+// DefineFlag 返回可注册到flag包的flag.Value, 如fs.Var(conf.DefineFlag(), "D", "set a property key=value"),
+// 之后即可使用"-D key=value"或"-D=key=value"覆盖配置, 来源为SourceCommandLine
+func (x *XmlConfig) DefineFlag() flag.Value {
+	return &defineFlag{x: x}
+}
+
+// String 实现flag.Value接口
+func (f *defineFlag) String() string {
+	return ""
+}
+
+// Set 实现flag.Value接口
+func (f *defineFlag) Set(s string) error {
+	if f.x == nil {
+		return errors.New("nil config")
+	}
+	key, value, err := parseDefine(s)
+	if err != nil {
+		return err
+	}
+	f.x.mu.Lock()
+	defer f.x.mu.Unlock()
+	return f.x.setValueFrom(key, value, SourceCommandLine)
+}
+
+// ApplyEnv 以prefix开头的环境变量覆盖对应的配置, mapping将去掉前缀的变量名转换为key,
+// 为nil时使用DefaultEnvKeyMapping, 如前缀为"HDFS_"时HDFS_DFS_REPLICATION覆盖dfs.replication.
+// 转换得到的key不存在时忽略大小写与已有的key匹配, 以便覆盖fs.defaultFS等含大写字母的key.
+// 与AddSource(EnvSource(...))不同, 覆盖的值位于所有资源之上并在Reload之后再次应用, 来源为"env 变量名".
+// 与ApplyArgs同时使用时应先调用ApplyEnv, 使命令行参数优先. 出错时的处理与ApplyArgs相同
+func (x *XmlConfig) ApplyEnv(prefix string, mapping func(name string) string) error {
+	props, err := EnvSource(prefix, mapping).Load()
+	if err != nil {
+		return err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var first error
+	for _, p := range props {
+		key := x.matchKey(p.Name)
+		if err := x.setValueFrom(key, p.RawValue, p.Source); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// matchKey 返回与key忽略大小写后相同的已有key, 不存在时返回key本身
+func (x *XmlConfig) matchKey(key string) string {
+	if _, ok := x.configurations[x.actualKey(key)]; ok {
+		return key
+	}
+	for _, k := range x.orderedKeys() {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
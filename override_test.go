@@ -0,0 +1,193 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newOverrideConfig(t *testing.T) *XmlConfig {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-default.xml", []byte(coreDefault)))
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(coreSite)))
+	return x
+}
+
+func TestXmlConfig_ApplyArgs(t *testing.T) {
+	useDeprecations(t, HadoopDeprecations)
+	tests := []struct {
+		name     string
+		args     []string
+		wantRest []string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "分开和连写",
+			args:     []string{"-D", "dfs.replication=2", "input", "-Dfs.defaultFS=hdfs://other:8020", "-v"},
+			wantRest: []string{"input", "-v"},
+			want:     map[string]string{"dfs.replication": "2", "fs.defaultFS": "hdfs://other:8020"},
+		},
+		{
+			name:     "值中的等号和空值",
+			args:     []string{"-Dopts=-Xmx1g -Da=b", "-D", "hadoop.tmp.dir="},
+			wantRest: nil,
+			want:     map[string]string{"opts": "-Xmx1g -Da=b", "hadoop.tmp.dir": ""},
+		},
+		{
+			name:     "--之后不解析",
+			args:     []string{"-Da=1", "--", "-Db=2"},
+			wantRest: []string{"-Db=2"},
+			want:     map[string]string{"a": "1", "b": ""},
+		},
+		{
+			name:     "弃用的key",
+			args:     []string{"-Dfs.default.name=hdfs://old"},
+			wantRest: nil,
+			want:     map[string]string{"fs.defaultFS": "hdfs://old"},
+		},
+		{
+			name:    "缺少值",
+			args:    []string{"-Da=1", "-D"},
+			wantErr: true,
+			want:    map[string]string{"a": ""},
+		},
+		{
+			name:    "缺少等号",
+			args:    []string{"-Da=1", "-Db"},
+			wantErr: true,
+			want:    map[string]string{"a": ""},
+		},
+		{
+			name:    "空key",
+			args:    []string{"-D=1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := newOverrideConfig(t)
+			rest, err := x.ApplyArgs(tt.args)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.wantRest, rest)
+			}
+			for key, value := range tt.want {
+				assert.Equal(t, value, x.GetString(key, ""), key)
+			}
+		})
+	}
+}
+
+func TestXmlConfig_ApplyArgsProvenance(t *testing.T) {
+	x := newOverrideConfig(t)
+	_, err := x.ApplyArgs([]string{"-Dfs.defaultFS=hdfs://cli:8020", "-Dio.file.buffer.size=1", "-Dhadoop.tmp.dir=/cli"})
+	assert.True(t, errors.Is(err, ErrFinalProperty))
+	assert.Equal(t, "4096", x.GetString("io.file.buffer.size", ""))
+	assert.Equal(t, "/cli", x.GetString("hadoop.tmp.dir", ""))
+	assert.Equal(t, []string{"core-default.xml", "core-site.xml", SourceCommandLine}, x.GetPropertySources("fs.defaultFS"))
+
+	// 重新加载和加入新的资源后覆盖的值仍然有效
+	assert.Nil(t, x.Reload())
+	assert.Equal(t, "hdfs://cli:8020", x.GetString("fs.defaultFS", ""))
+	assert.Equal(t, []string{"core-default.xml", "core-site.xml", SourceCommandLine}, x.GetPropertySources("fs.defaultFS"))
+	assert.Nil(t, x.AddSource(BytesSource("hdfs-site.xml", []byte(coreSite)), -1))
+	assert.Equal(t, "hdfs://cli:8020", x.GetString("fs.defaultFS", ""))
+	p, _ := x.Lookup("fs.defaultFS")
+	assert.Equal(t, SourceCommandLine, p.Source)
+}
+
+func TestXmlConfig_ApplyArgsSchema(t *testing.T) {
+	x := newOverrideConfig(t)
+	s, err := NewSchema(KeySchema{Name: "dfs.replication", Type: TypeInt, Min: "1"})
+	assert.Nil(t, err)
+	x.SetSchema(s)
+	_, err = x.ApplyArgs([]string{"-Ddfs.replication=0"})
+	var ve *ValueError
+	if assert.ErrorAs(t, err, &ve) {
+		assert.Equal(t, SourceCommandLine, ve.Source)
+	}
+}
+
+func TestXmlConfig_DefineFlag(t *testing.T) {
+	x := newOverrideConfig(t)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.Var(x.DefineFlag(), "D", "set a property key=value")
+	verbose := fs.Bool("v", false, "verbose")
+	assert.Nil(t, fs.Parse([]string{"-D", "dfs.replication=2", "-v", "-D=fs.defaultFS=hdfs://flag:8020", "input"}))
+	assert.True(t, *verbose)
+	assert.Equal(t, []string{"input"}, fs.Args())
+	assert.Equal(t, "2", x.GetString("dfs.replication", ""))
+	assert.Equal(t, "hdfs://flag:8020", x.GetString("fs.defaultFS", ""))
+	assert.Equal(t, []string{SourceCommandLine}, x.GetPropertySources("dfs.replication"))
+
+	assert.NotNil(t, fs.Parse([]string{"-D", "invalid"}))
+	assert.NotNil(t, fs.Parse([]string{"-D", "io.file.buffer.size=1"}))
+}
+
+func TestXmlConfig_ApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"XMLCONFIG_OVERRIDE_DFS_REPLICATION":            "2",
+		"XMLCONFIG_OVERRIDE_FS_DEFAULTFS":               "hdfs://env:8020",
+		"XMLCONFIG_OVERRIDE_DFS_NAMENODE_HTTP__ADDRESS": "nn:9870",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	x := newOverrideConfig(t)
+	assert.Nil(t, x.ApplyEnv("XMLCONFIG_OVERRIDE_", nil))
+	assert.Equal(t, "2", x.GetString("dfs.replication", ""))
+	assert.Equal(t, "hdfs://env:8020", x.GetString("fs.defaultFS", ""))
+	assert.Equal(t, "", x.GetString("fs.defaultfs", ""))
+	assert.Equal(t, "nn:9870", x.GetString("dfs.namenode.http-address", ""))
+	assert.Equal(t, []string{"core-default.xml", "core-site.xml", "env XMLCONFIG_OVERRIDE_FS_DEFAULTFS"}, x.GetPropertySources("fs.defaultFS"))
+
+	// 命令行参数覆盖环境变量
+	_, err := x.ApplyArgs([]string{"-Ddfs.replication=5"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"env XMLCONFIG_OVERRIDE_DFS_REPLICATION", SourceCommandLine}, x.GetPropertySources("dfs.replication"))
+	assert.Nil(t, x.Reload())
+	assert.Equal(t, "5", x.GetString("dfs.replication", ""))
+	assert.Equal(t, []string{SourceCommandLine}, x.GetPropertySources("dfs.replication"))
+	assert.Equal(t, "hdfs://env:8020", x.GetString("fs.defaultFS", ""))
+
+	y := newOverrideConfig(t)
+	assert.Nil(t, y.ApplyEnv("XMLCONFIG_OVERRIDE_", func(name string) string {
+		if name == "DFS_REPLICATION" {
+			return "dfs.replication"
+		}
+		return ""
+	}))
+	assert.Equal(t, "2", y.GetString("dfs.replication", ""))
+	assert.Equal(t, "hdfs://nn:8020", y.GetString("fs.defaultFS", ""))
+}
@@ -0,0 +1,253 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// BuildJavaPropertiesData 将配置构建为Java的.properties格式, 描述作为配置项前的#注释.
+// 转义规则与java.util.Properties.store相同, 非ASCII字符输出为\uXXXX
+func (x *XmlConfig) BuildJavaPropertiesData() ([]byte, error) {
+	var b bytes.Buffer
+	for _, p := range x.Properties() {
+		for _, line := range descriptionLines(p.Description) {
+			b.WriteString("# ")
+			b.WriteString(escapeJavaProperties(line, false, false))
+			b.WriteString("\n")
+		}
+		b.WriteString(escapeJavaProperties(p.Name, true, true))
+		b.WriteString("=")
+		b.WriteString(escapeJavaProperties(p.RawValue, false, true))
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
+
+// ParseJavaPropertiesData 解析Java的.properties格式的配置, 紧挨配置项之前的注释作为其描述.
+// 与ParseXmlData一样不加入资源栈
+func (x *XmlConfig) ParseJavaPropertiesData(data []byte) error {
+	props, err := decodeJavaProperties(data)
+	if err != nil {
+		return err
+	}
+	x.applyOnce(props, nil)
+	return nil
+}
+
+// descriptionLines 将描述按行分割并去除每行两侧的空白和首尾的空行
+func descriptionLines(description string) []string {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return nil
+	}
+	lines := strings.Split(description, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return lines
+}
+
+// escapeJavaProperties 按java.util.Properties.store的规则转义s, isKey为true时转义所有空格,
+// 否则只转义开头的空格. escapeSpecial为false时(用于注释)只转义非ASCII字符
+func escapeJavaProperties(s string, isKey, escapeSpecial bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case !escapeSpecial:
+			if r > 0x7e {
+				writeJavaUnicode(&b, r)
+			} else {
+				b.WriteRune(r)
+			}
+		case r == ' ':
+			if i == 0 || isKey {
+				b.WriteString(`\ `)
+			} else {
+				b.WriteRune(r)
+			}
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == '\\', r == '=', r == ':', r == '#', r == '!':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			writeJavaUnicode(&b, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// writeJavaUnicode 以\uXXXX输出r, 超出BMP的字符输出为UTF-16代理对
+func writeJavaUnicode(b *strings.Builder, r rune) {
+	for _, u := range utf16.Encode([]rune{r}) {
+		fmt.Fprintf(b, `\u%04X`, u)
+	}
+}
+
+// decodeJavaProperties 按java.util.Properties.load的规则解析.properties格式
+func decodeJavaProperties(data []byte) ([]Property, error) {
+	props := make([]Property, 0)
+	var comments []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimLeft(strings.TrimSuffix(sc.Text(), "\r"), " \t\f")
+		if line == "" {
+			comments = nil
+			continue
+		}
+		if line[0] == '#' || line[0] == '!' {
+			comments = append(comments, strings.TrimSpace(line[1:]))
+			continue
+		}
+		// 以奇数个反斜杠结尾的行与下一行相连, 下一行开头的空白被忽略
+		for endsWithContinuation(line) {
+			line = line[:len(line)-1]
+			if !sc.Scan() {
+				break
+			}
+			lineNo++
+			line += strings.TrimLeft(strings.TrimSuffix(sc.Text(), "\r"), " \t\f")
+		}
+		key, value := splitJavaProperty(line)
+		k, err := unescapeJavaProperties(key, false)
+		if err != nil {
+			return nil, &ParseError{Line: lineNo, Column: 1, Err: err}
+		}
+		v, err := unescapeJavaProperties(value, false)
+		if err != nil {
+			return nil, &ParseError{Line: lineNo, Column: 1, Property: k, Err: err}
+		}
+		description, err := unescapeJavaProperties(strings.Join(comments, "\n"), true)
+		if err != nil {
+			return nil, &ParseError{Line: lineNo, Column: 1, Property: k, Err: err}
+		}
+		props = append(props, Property{Name: k, Value: v, RawValue: v, Description: description})
+		comments = nil
+	}
+	return props, sc.Err()
+}
+
+// endsWithContinuation 判断line是否以奇数个反斜杠结尾
+func endsWithContinuation(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitJavaProperty 在第一个未转义的=、:或空白处分割key和value, 分隔符两侧的空白被忽略
+func splitJavaProperty(line string) (string, string) {
+	i := 0
+	for ; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+	}
+	if i >= len(line) {
+		return line, ""
+	}
+	key, rest := line[:i], strings.TrimLeft(line[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return key, rest
+}
+
+// unescapeJavaProperties 还原\uXXXX、\t、\n、\r、\f转义, 其他转义字符还原为其本身.
+// onlyUnicode为true时(用于注释)只还原\uXXXX
+func unescapeJavaProperties(s string, onlyUnicode bool) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var units []uint16
+	var b strings.Builder
+	flush := func() {
+		if len(units) > 0 {
+			b.WriteString(string(utf16.Decode(units)))
+			units = units[:0]
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i == len(s)-1 || (onlyUnicode && s[i+1] != 'u') {
+			flush()
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'u':
+			if i+5 <= len(s) {
+				if u, err := strconv.ParseUint(s[i+1:i+5], 16, 16); err == nil {
+					units = append(units, uint16(u))
+					i += 4
+					continue
+				}
+			}
+			if !onlyUnicode {
+				return "", fmt.Errorf("malformed \\uxxxx encoding: %q", s[i-1:])
+			}
+			flush()
+			b.WriteString(`\u`)
+		case 't':
+			flush()
+			b.WriteByte('\t')
+		case 'n':
+			flush()
+			b.WriteByte('\n')
+		case 'r':
+			flush()
+			b.WriteByte('\r')
+		case 'f':
+			flush()
+			b.WriteByte('\f')
+		default:
+			flush()
+			b.WriteByte(s[i])
+		}
+	}
+	flush()
+	return b.String(), nil
+}
@@ -0,0 +1,120 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXmlConfig_BuildJavaPropertiesData(t *testing.T) {
+	x := NewXmlConfig()
+	x.SetStringWithDescription("dfs.replication", "3", "Default block\nreplication.")
+	x.SetString("key with=:space", " leading\ttab\n")
+	x.SetString("unicode", "中文\x01")
+	x.SetString("hadoop.tmp.dir", "/tmp/${user}")
+	data, err := x.BuildJavaPropertiesData()
+	assert.Nil(t, err)
+	assert.Equal(t, `# Default block
+# replication.
+dfs.replication=3
+key\ with\=\:space=\ leading\ttab\n
+unicode=\u4E2D\u6587\u0001
+hadoop.tmp.dir=/tmp/${user}
+`, string(data))
+
+	live := NewXmlConfig()
+	assert.Nil(t, live.ParseJavaPropertiesData(data))
+	assert.Equal(t, x.GetConfigKeys(), live.GetConfigKeys())
+	assert.True(t, Diff(x, live).Empty())
+}
+
+func TestXmlConfig_ParseJavaPropertiesData(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Property
+	}{
+		{
+			name: "separators",
+			data: "a=1\nb:2\nc 3\nd\n  e = 5 ",
+			want: []Property{
+				{Name: "a", Value: "1", RawValue: "1"},
+				{Name: "b", Value: "2", RawValue: "2"},
+				{Name: "c", Value: "3", RawValue: "3"},
+				{Name: "d", Value: "", RawValue: ""},
+				{Name: "e", Value: "5 ", RawValue: "5 "},
+			},
+		},
+		{
+			name: "comments",
+			data: "# header\n\n! first\n# second \\u00e9\na=1\nb=2\n",
+			want: []Property{
+				{Name: "a", Value: "1", RawValue: "1", Description: "first\nsecond é"},
+				{Name: "b", Value: "2", RawValue: "2"},
+			},
+		},
+		{
+			name: "continuation",
+			data: "list=a,\\\n    b,\\\r\n    c\nescaped=x\\\\\n",
+			want: []Property{
+				{Name: "list", Value: "a,b,c", RawValue: "a,b,c"},
+				{Name: "escaped", Value: `x\`, RawValue: `x\`},
+			},
+		},
+		{
+			name: "escapes",
+			data: `k\ e\=y=\u4e2d\t\q\:`,
+			want: []Property{
+				{Name: "k e=y", Value: "中\tq:", RawValue: "中\tq:"},
+			},
+		},
+		{
+			name: "surrogate pair",
+			data: `emoji=\uD83D\uDE00`,
+			want: []Property{
+				{Name: "emoji", Value: "😀", RawValue: "😀"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewXmlConfig()
+			assert.Nil(t, x.ParseJavaPropertiesData([]byte(tt.data)))
+			props := x.Properties()
+			for i := range props {
+				props[i].Source = ""
+			}
+			assert.Equal(t, tt.want, props)
+		})
+	}
+}
+
+func TestXmlConfig_ParseJavaPropertiesDataError(t *testing.T) {
+	x := NewXmlConfig()
+	err := x.ParseJavaPropertiesData([]byte("a=1\nb=\\u12x4\n"))
+	var pe *ParseError
+	if assert.ErrorAs(t, err, &pe) {
+		assert.Equal(t, 2, pe.Line)
+	}
+}
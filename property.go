@@ -0,0 +1,104 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import "errors"
+
+// Property 配置项的只读视图
+type Property struct {
+	Name        string   `json:"name"`
+	Value       string   `json:"value"`    // 展开变量后的值
+	RawValue    string   `json:"rawValue"` // 未展开变量的原始值
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Final       bool     `json:"final,omitempty"`
+	Source      string   `json:"source,omitempty"` // 当前值的来源, 未知时为空
+}
+
+// toProperty 生成配置项的只读视图
+func (x *XmlConfig) toProperty(key string) Property {
+	p := x.configurations[key]
+	value, _, _ := x.expandKey(key)
+	return Property{
+		Name:        p.Name,
+		Value:       value,
+		RawValue:    p.Value,
+		Tags:        splitTags(p.Tag),
+		Description: p.Description,
+		Final:       p.Final,
+		Source:      x.currentSource(key),
+	}
+}
+
+// Lookup 获取key对应配置项的名称、值、tag、描述、final标记和来源
+func (x *XmlConfig) Lookup(key string) (Property, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	key = x.actualKey(key)
+	if _, ok := x.configurations[key]; !ok {
+		return Property{}, false
+	}
+	return x.toProperty(key), true
+}
+
+// Properties 按输出顺序获取所有配置项
+func (x *XmlConfig) Properties() []Property {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	keys := x.orderedKeys()
+	props := make([]Property, 0, len(keys))
+	for _, key := range keys {
+		props = append(props, x.toProperty(key))
+	}
+	return props
+}
+
+// SetDescription 更新key的描述, 描述在Reload之后会被再次应用
+func (x *XmlConfig) SetDescription(key string, description string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.setDescription(key, description)
+}
+
+// SetStringWithDescription 设置配置值及其描述, key不存在时创建, 被标记为final的key保持不变
+func (x *XmlConfig) SetStringWithDescription(key string, value string, description string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err := x.setValue(key, value); err == nil {
+		_ = x.setDescription(key, description)
+	}
+}
+
+func (x *XmlConfig) setDescription(key string, description string) error {
+	found := false
+	for _, k := range handleDeprecation(key) {
+		if p, ok := x.configurations[k]; ok {
+			p.Description = description
+			x.overlayDescription(k)
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("not exist key: " + key)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXmlConfig_Lookup(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-default.xml", []byte(`<configuration>
+    <property>
+        <name>hadoop.tmp.dir</name>
+        <value>/tmp/hadoop-${user.name}</value>
+        <tag>CORE, REQUIRED</tag>
+        <description>A base for other temporary directories.</description>
+        <final>true</final>
+    </property>
+    <property><name>user.name</name><value>hdfs</value></property>
+</configuration>`)))
+	x.SetStringWithDescription("dfs.replication", "2", "Default block replication.")
+	tests := []struct {
+		name   string
+		key    string
+		want   Property
+		wantOk bool
+	}{
+		{
+			name: "从资源加载",
+			key:  "hadoop.tmp.dir",
+			want: Property{
+				Name:        "hadoop.tmp.dir",
+				Value:       "/tmp/hadoop-hdfs",
+				RawValue:    "/tmp/hadoop-${user.name}",
+				Tags:        []string{"CORE", "REQUIRED"},
+				Description: "A base for other temporary directories.",
+				Final:       true,
+				Source:      "core-default.xml",
+			},
+			wantOk: true,
+		},
+		{
+			name: "通过代码设置",
+			key:  "dfs.replication",
+			want: Property{
+				Name:        "dfs.replication",
+				Value:       "2",
+				RawValue:    "2",
+				Description: "Default block replication.",
+				Source:      SourceProgrammatic,
+			},
+			wantOk: true,
+		},
+		{
+			name: "key不存在",
+			key:  "not.exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := x.Lookup(tt.key)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equalf(t, tt.want, got, "Lookup(%v)", tt.key)
+		})
+	}
+	props := x.Properties()
+	assert.Equal(t, 3, len(props))
+	assert.Equal(t, "user.name", props[1].Name)
+
+	assert.Nil(t, x.SetDescription("user.name", "The user."))
+	p, _ := x.Lookup("user.name")
+	assert.Equal(t, "The user.", p.Description)
+	assert.NotNil(t, x.SetDescription("not.exist", "desc"))
+}
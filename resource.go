@@ -0,0 +1,291 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+// SourceProgrammatic 通过SetString等方法设置的配置来源
+const SourceProgrammatic = "programmatically"
+
+// resource 资源栈中的一个配置资源
+type resource struct {
+	src      Source
+	priority int
+}
+
+// overlayEntry 通过代码设置的配置, 重新加载资源和加入新的资源后会再次应用
+type overlayEntry struct {
+	key string
+	// hasValue 为false时只再次应用元数据, 值使用资源中的值
+	hasValue bool
+	value    string
+	// source 为空时不记录来源
+	source string
+	// tag、description和final不为nil时再次应用后使用其中的内容
+	tag         *string
+	description *string
+	final       *bool
+}
+
+// AddResource 将xml文件加入资源栈并加载, 后加入的资源覆盖先加入的资源
+func (x *XmlConfig) AddResource(path string) error {
+	return x.AddSource(FileSource(path), 0)
+}
+
+// AddResourceData 将内存中的xml数据以name为名称加入资源栈并加载
+func (x *XmlConfig) AddResourceData(name string, data []byte) error {
+	return x.AddSource(BytesSource(name, data), 0)
+}
+
+// addResource 加载r并加入栈顶, r已不能位于栈顶时不做修改并返回false
+func (x *XmlConfig) addResource(r resource) (bool, error) {
+	l, err := r.load()
+	if err != nil {
+		return false, err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !isTop(x.resources, r.priority) {
+		return false, nil
+	}
+	if err := x.apply(l, r.src.Name()); err != nil {
+		return false, err
+	}
+	// 与Reload一样, 通过代码设置的配置总是覆盖资源中的配置
+	x.applyOverlay(x.overlay)
+	x.resources = append(x.resources, r)
+	x.resourceGen++
+	return true, nil
+}
+
+// GetResources 按加载顺序返回资源栈中的资源名称, 未命名的资源为空字符串
+func (x *XmlConfig) GetResources() []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	names := make([]string, 0, len(x.resources))
+	for _, r := range x.resources {
+		names = append(names, r.src.Name())
+	}
+	return names
+}
+
+// GetPropertySources 按设置顺序返回设置过key的资源, 最后一个为当前值的来源, key不存在时返回nil
+func (x *XmlConfig) GetPropertySources(key string) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	key = x.actualKey(key)
+	if _, ok := x.configurations[key]; !ok {
+		return nil
+	}
+	return append([]string(nil), x.sources[key]...)
+}
+
+// addSource 记录key的来源
+func (x *XmlConfig) addSource(key, source string) {
+	if source == "" {
+		return
+	}
+	if x.sources == nil {
+		x.sources = make(map[string][]string)
+	}
+	x.sources[key] = append(x.sources[key], source)
+}
+
+// currentSource 返回key当前值的来源
+func (x *XmlConfig) currentSource(key string) string {
+	if s := x.sources[key]; len(s) > 0 {
+		return s[len(s)-1]
+	}
+	return ""
+}
+
+// overlayFor 返回key在overlay中的记录, 不存在时新增
+func (x *XmlConfig) overlayFor(key string) *overlayEntry {
+	for i := range x.overlay {
+		if x.overlay[i].key == key {
+			return &x.overlay[i]
+		}
+	}
+	x.overlay = append(x.overlay, overlayEntry{key: key})
+	return &x.overlay[len(x.overlay)-1]
+}
+
+// overlayValue 记录通过代码设置的值及其来源
+func (x *XmlConfig) overlayValue(key, value, source string) {
+	e := x.overlayFor(key)
+	e.hasValue, e.value, e.source = true, value, source
+}
+
+// overlayTag 记录key当前的tag
+func (x *XmlConfig) overlayTag(key string) {
+	tag := x.configurations[key].Tag
+	x.overlayFor(key).tag = &tag
+}
+
+// overlayDescription 记录key当前的描述
+func (x *XmlConfig) overlayDescription(key string) {
+	description := x.configurations[key].Description
+	x.overlayFor(key).description = &description
+}
+
+// overlayFinal 记录key当前的final标记
+func (x *XmlConfig) overlayFinal(key string) {
+	final := x.configurations[key].Final
+	x.overlayFor(key).final = &final
+}
+
+// overlayMeta 记录key当前的tag、描述和final标记
+func (x *XmlConfig) overlayMeta(key string) {
+	x.overlayTag(key)
+	x.overlayDescription(key)
+	x.overlayFinal(key)
+}
+
+// applyOverlay 再次应用通过代码设置的配置, 被资源标记为final的key保持不变.
+// 值和来源都与当前相同的key不会重复记录来源
+func (x *XmlConfig) applyOverlay(overlay []overlayEntry) {
+	for _, o := range overlay {
+		p, ok := x.configurations[o.key]
+		if o.hasValue && (!ok || p.Value != o.value || x.currentSource(o.key) != o.source) {
+			if err := x.set(o.key, o.value); err != nil {
+				continue
+			}
+			x.addSource(o.key, o.source)
+			p, ok = x.configurations[o.key]
+		}
+		if !ok {
+			continue
+		}
+		if o.tag != nil {
+			p.Tag = *o.tag
+		}
+		if o.description != nil {
+			p.Description = *o.description
+		}
+		if o.final != nil {
+			p.Final = *o.final
+		}
+	}
+}
+
+// applyOnce 应用不加入资源栈的配置项, 已被标记为final的key不会被覆盖. 应用后的值与Set设置的值一样
+// 在Reload之后再次应用, doc不为nil时替换保留格式的文档
+func (x *XmlConfig) applyOnce(props []Property, doc *document) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	_ = x.apply(loaded{props: props}, "")
+	for _, p := range props {
+		value := p.RawValue
+		if value == "" {
+			value = p.Value
+		}
+		for _, key := range handleDeprecation(p.Name) {
+			// 被final跳过的配置项不需要再次应用, 同一个key出现多次时只记录最后一次
+			cur, ok := x.configurations[key]
+			if !ok || cur.Value != value {
+				continue
+			}
+			x.overlayValue(key, value, p.Source)
+			x.overlayMeta(key)
+		}
+	}
+	if doc != nil {
+		doc.layered = x.doc != nil
+		x.doc = doc
+	}
+}
+
+// unsetOverlay 删除通过代码设置的配置
+func (x *XmlConfig) unsetOverlay(key string) {
+	for i := range x.overlay {
+		if x.overlay[i].key == key {
+			x.overlay = append(x.overlay[:i], x.overlay[i+1:]...)
+			return
+		}
+	}
+}
+
+// Reload 按顺序重新加载资源栈中的所有资源并再次应用通过代码设置的配置,
+// 任一资源加载失败时返回错误并保留原有配置. 变化和错误会通知给Subscribe的订阅者
+func (x *XmlConfig) Reload() error {
+	e, err := x.reload(func(resources []resource) []resource { return resources })
+	if err != nil {
+		e.Err = err
+	}
+	x.notify(e)
+	return err
+}
+
+// reload 加载build根据当前资源栈生成的资源列表并替换当前的资源栈和配置.
+// 加载期间资源栈被其他调用修改时根据修改后的资源栈重新生成列表并再次加载
+func (x *XmlConfig) reload(build func(resources []resource) []resource) (ChangeEvent, error) {
+	for {
+		x.mu.RLock()
+		gen := x.resourceGen
+		resources := build(append([]resource(nil), x.resources...))
+		n := &XmlConfig{
+			configurations: make(map[string]*property),
+			maxExpandDepth: x.maxExpandDepth,
+			preserveFormat: x.preserveFormat,
+			strict:         x.strict,
+		}
+		x.mu.RUnlock()
+
+		var skipped []string
+		for i := range resources {
+			l, err := resources[i].load()
+			if err != nil {
+				return ChangeEvent{}, err
+			}
+			if err := n.apply(l, resources[i].src.Name()); err != nil {
+				return ChangeEvent{}, err
+			}
+			skipped = append(skipped, n.finalSkipped...)
+		}
+
+		if e, ok := x.replace(gen, resources, n, skipped); ok {
+			return e, nil
+		}
+	}
+}
+
+// replace 资源栈在gen之后没有变化时以n中加载的配置替换当前的资源栈和配置, 并再次应用通过代码设置的配置
+func (x *XmlConfig) replace(gen uint64, resources []resource, n *XmlConfig, skipped []string) (ChangeEvent, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.resourceGen != gen {
+		return ChangeEvent{}, false
+	}
+	old := x.snapshot()
+	n.applyOverlay(x.overlay)
+	n.schema = x.schema
+	n.fillDefaults()
+	x.resources = resources
+	x.resourceGen++
+	x.configurations = n.configurations
+	x.sources = n.sources
+	x.keys = n.keys
+	x.deprecatedInUse = n.deprecatedInUse
+	if x.preserveFormat && n.doc != nil {
+		x.doc = n.doc
+	}
+	x.finalSkipped = skipped
+	return diffSnapshot(old, x.snapshot()), true
+}
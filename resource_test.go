@@ -0,0 +1,236 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const coreDefault = `<configuration>
+    <property><name>fs.defaultFS</name><value>file:///</value></property>
+    <property><name>hadoop.tmp.dir</name><value>/tmp/hadoop</value></property>
+    <property><name>io.file.buffer.size</name><value>4096</value><final>true</final></property>
+</configuration>`
+
+const coreSite = `<configuration>
+    <property><name>fs.defaultFS</name><value>hdfs://nn:8020</value></property>
+    <property><name>io.file.buffer.size</name><value>65536</value></property>
+</configuration>`
+
+func writeTempXml(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestXmlConfig_GetPropertySources(t *testing.T) {
+	dir := t.TempDir()
+	defaultPath := writeTempXml(t, dir, "core-default.xml", coreDefault)
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResource(defaultPath))
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(coreSite)))
+	x.SetString("hadoop.tmp.dir", "/data/tmp")
+	tests := []struct {
+		name      string
+		key       string
+		wantValue string
+		want      []string
+	}{
+		{
+			name:      "被site覆盖",
+			key:       "fs.defaultFS",
+			wantValue: "hdfs://nn:8020",
+			want:      []string{defaultPath, "core-site.xml"},
+		},
+		{
+			name:      "被代码覆盖",
+			key:       "hadoop.tmp.dir",
+			wantValue: "/data/tmp",
+			want:      []string{defaultPath, SourceProgrammatic},
+		},
+		{
+			name:      "final不被覆盖",
+			key:       "io.file.buffer.size",
+			wantValue: "4096",
+			want:      []string{defaultPath},
+		},
+		{
+			name: "key不存在",
+			key:  "not.exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantValue, x.GetString(tt.key, ""))
+			assert.Equalf(t, tt.want, x.GetPropertySources(tt.key), "GetPropertySources(%v)", tt.key)
+		})
+	}
+	assert.Equal(t, []string{defaultPath, "core-site.xml"}, x.GetResources())
+}
+
+func TestXmlConfig_Reload(t *testing.T) {
+	dir := t.TempDir()
+	sitePath := writeTempXml(t, dir, "core-site.xml", coreSite)
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-default.xml", []byte(coreDefault)))
+	assert.Nil(t, x.ReadXmlFile(sitePath))
+	x.SetString("hadoop.tmp.dir", "/data/tmp")
+
+	writeTempXml(t, dir, "core-site.xml", `<configuration>
+    <property><name>fs.defaultFS</name><value>hdfs://nn2:8020</value></property>
+</configuration>`)
+	assert.Nil(t, x.Reload())
+	assert.Equal(t, "hdfs://nn2:8020", x.GetString("fs.defaultFS", ""))
+	assert.Equal(t, "/data/tmp", x.GetString("hadoop.tmp.dir", ""))
+	assert.Equal(t, []string{"core-default.xml", SourceProgrammatic}, x.GetPropertySources("hadoop.tmp.dir"))
+
+	writeTempXml(t, dir, "core-site.xml", "<configuration><property>")
+	assert.NotNil(t, x.Reload())
+	assert.Equal(t, "hdfs://nn2:8020", x.GetString("fs.defaultFS", ""))
+}
+
+// blockingSource gate设置为1后第一次加载时阻塞, 直到release被关闭
+type blockingSource struct {
+	gate    int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSource) Name() string {
+	return "blocking"
+}
+
+func (s *blockingSource) Load() ([]Property, error) {
+	if atomic.CompareAndSwapInt32(&s.gate, 1, 2) {
+		close(s.started)
+		<-s.release
+	}
+	return []Property{{Name: "blocking", RawValue: "1"}}, nil
+}
+
+func TestXmlConfig_Reload_ConcurrentAdd(t *testing.T) {
+	src := &blockingSource{started: make(chan struct{}), release: make(chan struct{})}
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddSource(src, 0))
+	atomic.StoreInt32(&src.gate, 1)
+
+	done := make(chan error)
+	go func() { done <- x.Reload() }()
+	<-src.started
+	// Reload加载期间加入的资源不能丢失
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(coreSite)))
+	assert.Nil(t, x.AddSource(BytesSource("low.xml", []byte(coreDefault)), -1))
+	close(src.release)
+	assert.Nil(t, <-done)
+
+	assert.Equal(t, []string{"low.xml", "blocking", "core-site.xml"}, x.GetResources())
+	assert.Equal(t, "hdfs://nn:8020", x.GetString("fs.defaultFS", ""))
+	assert.Equal(t, "1", x.GetString("blocking", ""))
+}
+
+func TestXmlConfig_AddResource_Overlay(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-default.xml", []byte(coreDefault)))
+	x.SetString("fs.defaultFS", "hdfs://local:8020")
+	assert.Nil(t, x.ParseXmlData([]byte(`<configuration>
+    <property><name>hadoop.tmp.dir</name><value>/data/tmp</value></property>
+</configuration>`)))
+	// 之后加入的资源不能覆盖通过代码设置的配置, 与Reload之后的结果一致
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(`<configuration>
+    <property><name>fs.defaultFS</name><value>hdfs://nn:8020</value></property>
+    <property><name>hadoop.tmp.dir</name><value>/site/tmp</value></property>
+</configuration>`)))
+	before := map[string]string{
+		"fs.defaultFS":   x.GetString("fs.defaultFS", ""),
+		"hadoop.tmp.dir": x.GetString("hadoop.tmp.dir", ""),
+	}
+	assert.Equal(t, map[string]string{"fs.defaultFS": "hdfs://local:8020", "hadoop.tmp.dir": "/data/tmp"}, before)
+	sources := x.GetPropertySources("fs.defaultFS")
+	assert.Equal(t, []string{"core-default.xml", SourceProgrammatic, "core-site.xml", SourceProgrammatic}, sources)
+
+	assert.Nil(t, x.Reload())
+	for key, value := range before {
+		assert.Equalf(t, value, x.GetString(key, ""), "GetString(%v)", key)
+	}
+}
+
+func TestXmlConfig_Reload_Tags(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(`<configuration>
+    <property><name>fs.defaultFS</name><value>hdfs://nn:8020</value><tag>CORE</tag></property>
+    <property><name>hadoop.tmp.dir</name><value>/tmp/hadoop</value><tag>CORE</tag></property>
+</configuration>`)))
+	x.SetStringWithTags("dfs.replication", "3", "HDFS")
+	assert.Nil(t, x.SetTags("fs.defaultFS", "HDFS", "SECURITY"))
+	assert.Nil(t, x.AddTags("hadoop.tmp.dir", "STORAGE"))
+
+	assert.Nil(t, x.Reload())
+	assert.Equal(t, []string{"HDFS"}, x.GetTags("dfs.replication"))
+	assert.Equal(t, []string{"HDFS", "SECURITY"}, x.GetTags("fs.defaultFS"))
+	assert.Equal(t, []string{"CORE", "STORAGE"}, x.GetTags("hadoop.tmp.dir"))
+	// 只设置了tag的key使用资源中的值
+	assert.Equal(t, "hdfs://nn:8020", x.GetString("fs.defaultFS", ""))
+	assert.Equal(t, []string{"core-site.xml"}, x.GetPropertySources("fs.defaultFS"))
+}
+
+func TestXmlConfig_Reload_Description(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(`<configuration>
+    <property><name>fs.defaultFS</name><value>hdfs://nn:8020</value><description>site</description></property>
+</configuration>`)))
+	x.SetStringWithDescription("dfs.replication", "3", "Default block replication.")
+	assert.Nil(t, x.SetDescription("fs.defaultFS", "The name of the default file system."))
+
+	assert.Nil(t, x.Reload())
+	p, _ := x.Lookup("dfs.replication")
+	assert.Equal(t, "Default block replication.", p.Description)
+	p, _ = x.Lookup("fs.defaultFS")
+	assert.Equal(t, "The name of the default file system.", p.Description)
+	assert.Equal(t, "hdfs://nn:8020", p.Value)
+}
+
+func TestXmlConfig_Reload_Final(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("core-default.xml", []byte(coreDefault)))
+	x.SetString("hadoop.tmp.dir", "/data/tmp")
+	assert.Nil(t, x.SetFinal("hadoop.tmp.dir", true))
+	assert.Nil(t, x.SetFinal("fs.defaultFS", true))
+	assert.Nil(t, x.SetFinal("io.file.buffer.size", false))
+
+	assert.Nil(t, x.Reload())
+	for key, final := range map[string]bool{"hadoop.tmp.dir": true, "fs.defaultFS": true, "io.file.buffer.size": false} {
+		p, _ := x.Lookup(key)
+		assert.Equalf(t, final, p.Final, "Lookup(%v).Final", key)
+	}
+	assert.Equal(t, "/data/tmp", x.GetString("hadoop.tmp.dir", ""))
+	// 通过代码标记为final的key不会被之后加入的资源覆盖
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(coreSite)))
+	assert.Equal(t, "file:///", x.GetString("fs.defaultFS", ""))
+	assert.Equal(t, "65536", x.GetString("io.file.buffer.size", ""))
+}
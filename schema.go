@@ -0,0 +1,351 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceSchemaDefault 由schema默认值填充的配置来源
+const SourceSchemaDefault = "schema default"
+
+var (
+	// ErrRequired schema中要求的key不存在
+	ErrRequired = errors.New("required key is missing")
+	// ErrInvalidType 配置值不是schema要求的类型
+	ErrInvalidType = errors.New("invalid value type")
+	// ErrOutOfRange 配置值不在schema要求的范围内
+	ErrOutOfRange = errors.New("value out of range")
+	// ErrNotAllowed 配置值不是schema允许的值之一
+	ErrNotAllowed = errors.New("value not allowed")
+)
+
+// ValueType schema中配置值的类型
+type ValueType string
+
+const (
+	// TypeString 任意字符串, 空类型等同于TypeString
+	TypeString ValueType = "string"
+	// TypeInt 64位整数
+	TypeInt ValueType = "int"
+	// TypeFloat 浮点数
+	TypeFloat ValueType = "float"
+	// TypeBool true或false, 不区分大小写
+	TypeBool ValueType = "bool"
+	// TypeDuration 时间, 格式同GetDuration, 不带后缀的数字以毫秒为单位
+	TypeDuration ValueType = "duration"
+	// TypeStorageSize 容量, 格式同GetBytes
+	TypeStorageSize ValueType = "size"
+	// TypeURI 带scheme的URI, 如hdfs://nn:8020
+	TypeURI ValueType = "uri"
+)
+
+// KeySchema 一个key的类型、范围、默认值等约束. Min和Max按Type解析,
+// 如int类型的"1"、duration类型的"1s"、size类型的"128m", 为空时不限制
+type KeySchema struct {
+	Name        string    `xml:"name" json:"name"`
+	Type        ValueType `xml:"type,omitempty" json:"type,omitempty"`
+	Min         string    `xml:"min,omitempty" json:"min,omitempty"`
+	Max         string    `xml:"max,omitempty" json:"max,omitempty"`
+	Default     string    `xml:"default,omitempty" json:"default,omitempty"`
+	Required    bool      `xml:"required,omitempty" json:"required,omitempty"`
+	Allowed     []string  `xml:"allowed,omitempty" json:"allowed,omitempty"`
+	Description string    `xml:"description,omitempty" json:"description,omitempty"`
+}
+
+// Schema 一组key的约束, 创建后不可修改
+type Schema struct {
+	keys  []KeySchema
+	index map[string]*compiledKey
+}
+
+// compiledKey 解析过范围的KeySchema
+type compiledKey struct {
+	KeySchema
+	min, max *float64
+}
+
+// SchemaError 配置不满足schema时的所有错误, 按schema中key的顺序排列
+type SchemaError struct {
+	Errors []*ValueError
+}
+
+// Error 实现error接口
+func (e *SchemaError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d schema violation(s): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Is 任一错误匹配target时返回true
+func (e *SchemaError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSchema 创建schema, key重复、类型未知、范围无法解析或默认值不满足约束时返回错误
+func NewSchema(keys ...KeySchema) (*Schema, error) {
+	s := &Schema{index: make(map[string]*compiledKey, len(keys))}
+	for _, k := range keys {
+		if k.Name == "" {
+			return nil, errors.New("schema key without name")
+		}
+		if _, ok := s.index[k.Name]; ok {
+			return nil, fmt.Errorf("duplicate schema key: %s", k.Name)
+		}
+		if k.Type == "" {
+			k.Type = TypeString
+		}
+		c := &compiledKey{KeySchema: k}
+		switch k.Type {
+		case TypeString, TypeBool, TypeURI:
+			if k.Min != "" || k.Max != "" {
+				return nil, fmt.Errorf("schema key %s: range is not supported for type %s", k.Name, k.Type)
+			}
+		case TypeInt, TypeFloat, TypeDuration, TypeStorageSize:
+			var err error
+			if c.min, err = c.bound(k.Min); err != nil {
+				return nil, fmt.Errorf("schema key %s: invalid min: %w", k.Name, err)
+			}
+			if c.max, err = c.bound(k.Max); err != nil {
+				return nil, fmt.Errorf("schema key %s: invalid max: %w", k.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("schema key %s: unknown type %q", k.Name, k.Type)
+		}
+		if k.Default != "" {
+			if err := c.check(k.Default); err != nil {
+				return nil, fmt.Errorf("schema key %s: invalid default: %w", k.Name, err)
+			}
+		}
+		s.keys = append(s.keys, k)
+		s.index[k.Name] = c
+	}
+	return s, nil
+}
+
+// ParseSchemaXml 从xml中解析schema, 格式如
+//
+//	<schema>
+//	    <key>
+//	        <name>dfs.replication</name>
+//	        <type>int</type>
+//	        <min>1</min>
+//	        <max>512</max>
+//	        <default>3</default>
+//	    </key>
+//	</schema>
+func ParseSchemaXml(data []byte) (*Schema, error) {
+	var doc struct {
+		XMLName xml.Name    `xml:"schema"`
+		Keys    []KeySchema `xml:"key"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return NewSchema(doc.Keys...)
+}
+
+// ParseSchemaJson 从json中解析schema, 格式如{"keys":[{"name":"dfs.replication","type":"int","min":"1","max":"512","default":"3"}]}
+func ParseSchemaJson(data []byte) (*Schema, error) {
+	var doc struct {
+		Keys []KeySchema `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return NewSchema(doc.Keys...)
+}
+
+// LoadSchemaFile 从文件中加载schema, 扩展名为.json时按json解析, 否则按xml解析
+func LoadSchemaFile(path string) (*Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseSchemaJson(data)
+	}
+	return ParseSchemaXml(data)
+}
+
+// Keys 按注册顺序返回schema中的所有key
+func (s *Schema) Keys() []KeySchema {
+	return append([]KeySchema(nil), s.keys...)
+}
+
+// Check 检查value是否满足key的约束, schema中没有key时返回nil
+func (s *Schema) Check(key, value string) error {
+	if c, ok := s.index[key]; ok {
+		return c.check(value)
+	}
+	return nil
+}
+
+// bound 解析范围边界, 为空时返回nil
+func (c *compiledKey) bound(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	n, err := c.number(s)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// number 将数值类型的配置值转换为可比较的数字, 时间为纳秒, 容量为字节
+func (c *compiledKey) number(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	switch c.Type {
+	case TypeInt:
+		i, err := strconv.ParseInt(s, 10, 64)
+		return float64(i), err
+	case TypeDuration:
+		d, err := parseDuration(s, time.Millisecond)
+		return float64(d), err
+	case TypeStorageSize:
+		b, err := parseBytes(s, Byte)
+		return float64(b), err
+	default:
+		return strconv.ParseFloat(s, 64)
+	}
+}
+
+// check 检查value是否满足约束
+func (c *compiledKey) check(value string) error {
+	switch c.Type {
+	case TypeBool:
+		if v := strings.TrimSpace(value); !strings.EqualFold(v, "true") && !strings.EqualFold(v, "false") {
+			return fmt.Errorf("%w: expected %s", ErrInvalidType, c.Type)
+		}
+	case TypeURI:
+		u, err := url.Parse(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%w: expected %s: %v", ErrInvalidType, c.Type, err)
+		}
+		if u.Scheme == "" {
+			return fmt.Errorf("%w: expected %s with scheme", ErrInvalidType, c.Type)
+		}
+	case TypeInt, TypeFloat, TypeDuration, TypeStorageSize:
+		n, err := c.number(value)
+		if err != nil {
+			return fmt.Errorf("%w: expected %s: %v", ErrInvalidType, c.Type, err)
+		}
+		if (c.min != nil && n < *c.min) || (c.max != nil && n > *c.max) {
+			return fmt.Errorf("%w: expected [%s, %s]", ErrOutOfRange, c.Min, c.Max)
+		}
+	}
+	if len(c.Allowed) > 0 {
+		for _, a := range c.Allowed {
+			if a == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: expected one of %s", ErrNotAllowed, strings.Join(c.Allowed, ", "))
+	}
+	return nil
+}
+
+// SetSchema 设置schema并为不存在的key填充默认值, 默认值的来源为SourceSchemaDefault.
+// 之后Set和SetString设置不满足schema的值会被拒绝, Reload后会再次填充默认值. s为nil时取消schema
+func (x *XmlConfig) SetSchema(s *Schema) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.schema = s
+	x.fillDefaults()
+}
+
+// fillDefaults 为不存在的key填充schema中的默认值
+func (x *XmlConfig) fillDefaults() {
+	if x.schema == nil {
+		return
+	}
+	for _, k := range x.schema.keys {
+		if k.Default == "" {
+			continue
+		}
+		if _, ok := x.configurations[x.actualKey(k.Name)]; !ok {
+			_ = x.set(k.Name, k.Default)
+			x.addSource(k.Name, SourceSchemaDefault)
+		}
+	}
+}
+
+// checkSchema 检查将key设置为value后是否满足schema, value中的引用无法完全展开时不检查
+func (x *XmlConfig) checkSchema(key, value, source string) error {
+	if x.schema == nil {
+		return nil
+	}
+	expanded, err := x.expand(value, []string{key})
+	if err != nil || strings.Contains(expanded, "${") {
+		return nil
+	}
+	if err := x.schema.Check(key, expanded); err != nil {
+		return &ValueError{Key: key, Value: value, Source: source, Err: err}
+	}
+	return nil
+}
+
+// ValidateSchema 检查当前配置是否满足schema, 返回包含所有错误的*SchemaError, 未设置schema时返回nil
+func (x *XmlConfig) ValidateSchema() error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.schema == nil {
+		return nil
+	}
+	var errs []*ValueError
+	for _, k := range x.schema.keys {
+		value, ok, err := x.expandKey(k.Name)
+		if !ok {
+			if k.Required {
+				errs = append(errs, &ValueError{Key: k.Name, Err: ErrRequired})
+			}
+			continue
+		}
+		if err == nil {
+			err = x.schema.Check(k.Name, value)
+		}
+		if err != nil {
+			errs = append(errs, x.valueError(k.Name, value, err).(*ValueError))
+		}
+	}
+	if len(errs) > 0 {
+		return &SchemaError{Errors: errs}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newHdfsSchema(t *testing.T) *Schema {
+	s, err := NewSchema(
+		KeySchema{Name: "dfs.replication", Type: TypeInt, Min: "1", Max: "512", Default: "3"},
+		KeySchema{Name: "dfs.blocksize", Type: TypeStorageSize, Min: "1m", Default: "128m"},
+		KeySchema{Name: "dfs.heartbeat.interval", Type: TypeDuration, Max: "1h"},
+		KeySchema{Name: "dfs.permissions.enabled", Type: TypeBool},
+		KeySchema{Name: "dfs.checksum.type", Allowed: []string{"CRC32", "CRC32C"}},
+		KeySchema{Name: "fs.defaultFS", Type: TypeURI, Required: true},
+	)
+	assert.Nil(t, err)
+	return s
+}
+
+func TestSchema_Check(t *testing.T) {
+	s := newHdfsSchema(t)
+	tests := []struct {
+		key     string
+		value   string
+		wantErr error
+	}{
+		{"dfs.replication", "3", nil},
+		{"dfs.replication", " 512 ", nil},
+		{"dfs.replication", "0", ErrOutOfRange},
+		{"dfs.replication", "513", ErrOutOfRange},
+		{"dfs.replication", "abc", ErrInvalidType},
+		{"dfs.blocksize", "256m", nil},
+		{"dfs.blocksize", "1k", ErrOutOfRange},
+		{"dfs.blocksize", "1x", ErrInvalidType},
+		{"dfs.heartbeat.interval", "3s", nil},
+		{"dfs.heartbeat.interval", "2h", ErrOutOfRange},
+		{"dfs.permissions.enabled", "TRUE", nil},
+		{"dfs.permissions.enabled", "yes", ErrInvalidType},
+		{"dfs.checksum.type", "CRC32C", nil},
+		{"dfs.checksum.type", "MD5", ErrNotAllowed},
+		{"fs.defaultFS", "hdfs://nn:8020", nil},
+		{"fs.defaultFS", "hdfs://nn:port", ErrInvalidType},
+		{"fs.defaultFS", "/tmp", ErrInvalidType},
+		{"not.in.schema", "anything", nil},
+	}
+	for _, tt := range tests {
+		err := s.Check(tt.key, tt.value)
+		if tt.wantErr == nil {
+			assert.Nil(t, err, "Check(%v, %v)", tt.key, tt.value)
+		} else {
+			assert.True(t, errors.Is(err, tt.wantErr), "Check(%v, %v): %v", tt.key, tt.value, err)
+		}
+	}
+}
+
+func TestNewSchema_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []KeySchema
+	}{
+		{"缺少名称", []KeySchema{{Type: TypeInt}}},
+		{"重复的key", []KeySchema{{Name: "a"}, {Name: "a"}}},
+		{"未知类型", []KeySchema{{Name: "a", Type: "date"}}},
+		{"不支持范围", []KeySchema{{Name: "a", Type: TypeBool, Min: "1"}}},
+		{"无法解析的范围", []KeySchema{{Name: "a", Type: TypeInt, Max: "1s"}}},
+		{"默认值超出范围", []KeySchema{{Name: "a", Type: TypeInt, Max: "10", Default: "11"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSchema(tt.keys...)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestLoadSchemaFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"schema.xml": `<schema>
+    <key>
+        <name>dfs.replication</name>
+        <type>int</type>
+        <min>1</min>
+        <max>512</max>
+        <default>3</default>
+    </key>
+    <key>
+        <name>fs.defaultFS</name>
+        <type>uri</type>
+        <required>true</required>
+    </key>
+    <key>
+        <name>dfs.checksum.type</name>
+        <allowed>CRC32</allowed>
+        <allowed>CRC32C</allowed>
+    </key>
+</schema>`,
+		"schema.json": `{"keys": [
+    {"name": "dfs.replication", "type": "int", "min": "1", "max": "512", "default": "3"},
+    {"name": "fs.defaultFS", "type": "uri", "required": true},
+    {"name": "dfs.checksum.type", "allowed": ["CRC32", "CRC32C"]}
+]}`,
+	}
+	want := []KeySchema{
+		{Name: "dfs.replication", Type: TypeInt, Min: "1", Max: "512", Default: "3"},
+		{Name: "fs.defaultFS", Type: TypeURI, Required: true},
+		{Name: "dfs.checksum.type", Type: TypeString, Allowed: []string{"CRC32", "CRC32C"}},
+	}
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			assert.Nil(t, os.WriteFile(path, []byte(data), 0644))
+			s, err := LoadSchemaFile(path)
+			assert.Nil(t, err)
+			assert.Equal(t, want, s.Keys())
+		})
+	}
+	_, err := LoadSchemaFile(filepath.Join(dir, "not-exist.json"))
+	assert.NotNil(t, err)
+	_, err = ParseSchemaJson([]byte(`{"keys": [{"name": "a", "type": "int", "default": "x"}]}`))
+	assert.NotNil(t, err)
+	_, err = ParseSchemaXml([]byte(`<keys></keys>`))
+	assert.NotNil(t, err)
+}
+
+func TestXmlConfig_SetSchema(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("hdfs-site.xml", []byte(`<configuration>
+    <property><name>dfs.blocksize</name><value>256m</value></property>
+    <property><name>dfs.heartbeat.interval</name><value>2h</value></property>
+</configuration>`)))
+	x.SetSchema(newHdfsSchema(t))
+
+	assert.Equal(t, "3", x.GetString("dfs.replication", ""))
+	assert.Equal(t, []string{SourceSchemaDefault}, x.GetPropertySources("dfs.replication"))
+	assert.Equal(t, "256m", x.GetString("dfs.blocksize", ""))
+
+	err := x.ValidateSchema()
+	var se *SchemaError
+	if assert.True(t, errors.As(err, &se), "%v", err) {
+		assert.Len(t, se.Errors, 2)
+		assert.Equal(t, "dfs.heartbeat.interval", se.Errors[0].Key)
+		assert.Equal(t, "hdfs-site.xml", se.Errors[0].Source)
+		assert.True(t, errors.Is(se.Errors[0], ErrOutOfRange))
+		assert.Equal(t, "fs.defaultFS", se.Errors[1].Key)
+		assert.True(t, errors.Is(se.Errors[1], ErrRequired))
+	}
+
+	err = x.Set("dfs.replication", "1000")
+	var ve *ValueError
+	assert.True(t, errors.As(err, &ve))
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+	x.SetString("dfs.replication", "abc")
+	assert.Equal(t, "3", x.GetString("dfs.replication", ""))
+	x.SetString("dfs.replication", "${replication}")
+	assert.Equal(t, "${replication}", x.GetString("dfs.replication", ""))
+	x.SetString("dfs.replication", "2")
+	assert.Equal(t, "2", x.GetString("dfs.replication", ""))
+	assert.Nil(t, x.Set("fs.defaultFS", "hdfs://nn:8020"))
+	x.SetString("dfs.heartbeat.interval", "3s")
+	assert.Nil(t, x.ValidateSchema())
+
+	x.unset("dfs.replication")
+	assert.Nil(t, x.Reload())
+	assert.Equal(t, "3", x.GetString("dfs.replication", ""))
+	assert.Equal(t, "hdfs://nn:8020", x.GetString("fs.defaultFS", ""))
+
+	x.SetSchema(nil)
+	assert.Nil(t, x.ValidateSchema())
+	assert.Nil(t, x.Set("dfs.replication", "1000"))
+}
@@ -23,15 +23,54 @@ package xmlconfig
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"time"
 )
 
-// SetString TODO
-func (x *XmlConfig) SetString(key string, value string) {
-	if _, ok := x.configurations[key]; ok {
-		x.configurations[key].Value = value
+// ErrFinalProperty 配置被标记为final, 不允许修改
+var ErrFinalProperty = errors.New("final property cannot be modified")
+
+// Set 设置配置值, key被标记为final时返回ErrFinalProperty, 值不满足SetSchema设置的schema时返回*ValueError,
+// 出错时不做修改. 设置的值在Reload之后会被再次应用
+func (x *XmlConfig) Set(key string, value string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.setValue(key, value)
+}
+
+// setValue 设置配置值并记录来源, 弃用的key会被转换为新key
+func (x *XmlConfig) setValue(key string, value string) error {
+	return x.setValueFrom(key, value, SourceProgrammatic)
+}
+
+// setValueFrom 以source为来源设置配置值, 设置的值在Reload之后会被再次应用
+func (x *XmlConfig) setValueFrom(key, value, source string) error {
+	for _, k := range handleDeprecation(key) {
+		if err := x.checkSchema(k, value, source); err != nil {
+			return err
+		}
+		if err := x.set(k, value); err != nil {
+			return err
+		}
+		x.overlayValue(k, value, source)
+		x.addSource(k, source)
+	}
+	return nil
+}
+
+// set 设置配置值, 不记录来源
+func (x *XmlConfig) set(key string, value string) error {
+	if p, ok := x.configurations[key]; ok {
+		if p.Final {
+			return fmt.Errorf("%w: %s", ErrFinalProperty, key)
+		}
+		p.Value = value
 	} else {
+		x.addKey(key)
 		x.configurations[key] = &property{
 			XMLName:     xml.Name{Local: "property"},
 			Name:        key,
@@ -40,6 +79,85 @@ func (x *XmlConfig) SetString(key string, value string) {
 			Description: "",
 		}
 	}
+	return nil
+}
+
+// SetString 设置配置值, 被标记为final或值不满足schema时key保持不变
+func (x *XmlConfig) SetString(key string, value string) {
+	_ = x.Set(key, value)
+}
+
+// SetStringWithTags 设置配置值并为其设置tag, 被标记为final的key保持不变. tag不区分大小写, 重复的tag只保留第一个
+func (x *XmlConfig) SetStringWithTags(key string, value string, tags ...string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err := x.setValue(key, value); err == nil {
+		_ = x.setTags(key, tags)
+	}
+}
+
+// SetTags 设置key的tag, 替换原有的tag, 不区分大小写重复的tag只保留第一个. 设置的tag在Reload之后会被再次应用
+func (x *XmlConfig) SetTags(key string, tags ...string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.setTags(key, tags)
+}
+
+// AddTags 为key追加tag, 已有的tag和tags中重复的tag不会重复添加, tag不区分大小写
+func (x *XmlConfig) AddTags(key string, tags ...string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	p, ok := x.configurations[x.actualKey(key)]
+	if !ok {
+		return errors.New("not exist key: " + key)
+	}
+	return x.setTags(key, append(splitTags(p.Tag), tags...))
+}
+
+// containsTag 判断tags中是否包含tag, 不区分大小写
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// setTags 设置tag并去除不区分大小写重复的tag, 设置的tag在Reload之后会被再次应用
+func (x *XmlConfig) setTags(key string, tags []string) error {
+	var unique []string
+	for _, tag := range splitTags(strings.Join(tags, ",")) {
+		if !containsTag(unique, tag) {
+			unique = append(unique, tag)
+		}
+	}
+	found := false
+	for _, k := range handleDeprecation(key) {
+		if p, ok := x.configurations[k]; ok {
+			p.Tag = strings.Join(unique, ",")
+			x.overlayTag(k)
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("not exist key: " + key)
+	}
+	return nil
+}
+
+// SetFinal 设置key的final标记, final的key不会被SetString和之后加载的配置覆盖. 标记在Reload之后会被再次应用
+func (x *XmlConfig) SetFinal(key string, final bool) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	k := x.actualKey(key)
+	p, ok := x.configurations[k]
+	if !ok {
+		return errors.New("not exist key: " + key)
+	}
+	p.Final = final
+	x.overlayFinal(k)
+	return nil
 }
 
 // SetBool TODO
@@ -61,16 +179,39 @@ func (x *XmlConfig) SetUint(key string, value uint64) {
 	x.SetString(key, strconv.FormatUint(value, 10))
 }
 
+// SetDuration 以能精确表示value的最大单位设置时间配置, 如30s、5m、200ms
+func (x *XmlConfig) SetDuration(key string, value time.Duration) {
+	x.SetString(key, formatDuration(value))
+}
+
+// SetBytes 以能精确表示value的最大单位设置容量配置, 如134217728设置为128m
+func (x *XmlConfig) SetBytes(key string, value int64) {
+	x.SetString(key, formatBytes(value))
+}
+
+// SetStorageSize 设置以unit为单位的容量配置, 换算为字节后向最近的整数取整
+func (x *XmlConfig) SetStorageSize(key string, value float64, unit StorageUnit) {
+	x.SetBytes(key, int64(math.Round(value*float64(unit))))
+}
+
 // SetIfUnset TODO
 func (x *XmlConfig) SetIfUnset(key string, value string) {
-	if _, ok := x.configurations[key]; !ok {
-		fmt.Println(x.configurations[key])
-		x.SetString(key, value)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.configurations[x.actualKey(key)]; !ok {
+		_ = x.setValue(key, value)
 	}
 }
 
 func (x *XmlConfig) unset(key string) {
-	if _, ok := x.configurations[key]; ok {
-		delete(x.configurations, key)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, k := range handleDeprecation(key) {
+		if p, ok := x.configurations[k]; ok && !p.Final {
+			delete(x.configurations, k)
+			delete(x.sources, k)
+			x.removeKey(k)
+			x.unsetOverlay(k)
+		}
 	}
 }
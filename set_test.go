@@ -22,7 +22,9 @@
 package xmlconfig
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -265,3 +267,70 @@ func TestXmlConfig_unset(t *testing.T) {
 		})
 	}
 }
+
+func TestXmlConfig_Set_Final(t *testing.T) {
+	x := &XmlConfig{
+		configurations: newConfigurations(),
+	}
+	assert.Nil(t, x.SetFinal("name1", true))
+	assert.True(t, errors.Is(x.Set("name1", "val"), ErrFinalProperty))
+	x.SetString("name1", "val")
+	x.unset("name1")
+	value, _ := x.Get("name1")
+	assert.Equal(t, "value1", value)
+	assert.Nil(t, x.SetFinal("name1", false))
+	assert.Nil(t, x.Set("name1", "val"))
+	value, _ = x.Get("name1")
+	assert.Equal(t, "val", value)
+	assert.NotNil(t, x.SetFinal("name2", true))
+}
+
+func TestXmlConfig_SetDuration(t *testing.T) {
+	x := &XmlConfig{
+		configurations: newConfigurations(),
+	}
+	x.SetDuration("name1", 90*time.Minute)
+	value, _ := x.Get("name1")
+	assert.Equal(t, "90m", value)
+	d, err := x.GetDuration("name1", 0, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+}
+
+func TestXmlConfig_SetStorageSize(t *testing.T) {
+	x := &XmlConfig{
+		configurations: newConfigurations(),
+	}
+	x.SetStorageSize("name1", 1.5, GB)
+	value, _ := x.Get("name1")
+	assert.Equal(t, "1536m", value)
+	x.SetBytes("name1", 1000)
+	value, _ = x.Get("name1")
+	assert.Equal(t, "1000", value)
+}
+
+func TestXmlConfig_SetTags(t *testing.T) {
+	x := &XmlConfig{
+		configurations: newConfigurations(),
+	}
+	x.SetStringWithTags("name2", "value2", "HDFS", " SECURITY ")
+	assert.Equal(t, []string{"HDFS", "SECURITY"}, x.GetTags("name2"))
+	assert.Equal(t, map[string]string{"name2": "value2"}, x.GetPropsByTag("security"))
+
+	assert.Nil(t, x.AddTags("name1", "tag2", "tag3"))
+	assert.Equal(t, []string{"tag1", "tag2", "tag3"}, x.GetTags("name1"))
+	assert.Nil(t, x.AddTags("name1", "a", "a", "A", "TAG1,tag4"))
+	assert.Equal(t, []string{"tag1", "tag2", "tag3", "a", "tag4"}, x.GetTags("name1"))
+	assert.Equal(t, []string{"HDFS", "SECURITY", "a", "tag1", "tag2", "tag3", "tag4"}, x.GetAllTags())
+	// 大小写不同的tag只保留按配置顺序第一次出现的写法, name4在name1之前
+	x.SetStringWithTags("name4", "value4", "hdfs", "Tag2")
+	assert.Equal(t, []string{"HDFS", "SECURITY", "Tag2", "a", "tag1", "tag3", "tag4"}, x.GetAllTags())
+	assert.Nil(t, x.SetTags("name1", "tag4"))
+	assert.Equal(t, []string{"tag4"}, x.GetTags("name1"))
+	x.SetStringWithTags("m", "v", "A", "a", "B")
+	assert.Equal(t, []string{"A", "B"}, x.GetTags("m"))
+	assert.Nil(t, x.SetTags("m", "b,B", "c"))
+	assert.Equal(t, []string{"b", "c"}, x.GetTags("m"))
+	assert.NotNil(t, x.SetTags("name3", "tag4"))
+	assert.NotNil(t, x.AddTags("name3", "tag4"))
+}
@@ -0,0 +1,478 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Source 配置来源, 通过XmlConfig.AddSource加入资源栈
+type Source interface {
+	// Name 返回来源名称, 用作配置项的默认来源
+	Name() string
+	// Load 加载配置项, 以Property.RawValue作为配置值(为空时使用Value),
+	// Property.Source为空时使用Name作为来源
+	Load() ([]Property, error)
+}
+
+// WatchableSource 可检测变化的配置来源, Watch在Version变化时重新加载
+type WatchableSource interface {
+	Source
+	Version() (string, error)
+}
+
+// xmlDataSource 以单个xml文档提供配置的来源, 加载时走与ParseXmlData相同的解析流程
+type xmlDataSource interface {
+	Source
+	loadXml() ([]byte, error)
+}
+
+// xmlFilesSource 以多个xml文档提供配置的来源, 每个文档都走与ParseXmlData相同的解析流程
+type xmlFilesSource interface {
+	Source
+	loadXmlFiles() ([]xmlDoc, error)
+}
+
+// xmlDoc 一个xml文档及其名称
+type xmlDoc struct {
+	name string
+	data []byte
+}
+
+// xmlSource 内置的xml来源
+type xmlSource struct {
+	name    string
+	load    func() ([]byte, error)
+	version func() (string, error)
+}
+
+// Name 实现Source接口
+func (s *xmlSource) Name() string {
+	return s.name
+}
+
+// Load 实现Source接口
+func (s *xmlSource) Load() ([]Property, error) {
+	data, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return parseProperties(data, s.name)
+}
+
+func (s *xmlSource) loadXml() ([]byte, error) {
+	return s.load()
+}
+
+// watchableXmlSource 可检测变化的内置xml来源
+type watchableXmlSource struct {
+	xmlSource
+}
+
+// Version 实现WatchableSource接口
+func (s *watchableXmlSource) Version() (string, error) {
+	return s.version()
+}
+
+// ParseProperties 解析xml配置得到所有配置项, 不处理final和弃用key, Value与RawValue均为原始值.
+// 解析失败时返回*ParseError
+func ParseProperties(data []byte) ([]Property, error) {
+	return parseProperties(data, "")
+}
+
+// parseProperties 解析xml配置, source为出错时报告的资源名称
+func parseProperties(data []byte, source string) ([]Property, error) {
+	props, err := decodeXml(bytes.NewReader(data), source, false)
+	if err != nil {
+		return nil, err
+	}
+	return toProperties(props), nil
+}
+
+// FileSource 从本地xml文件加载配置, 文件修改时间或大小变化时视为有变化
+func FileSource(path string) Source {
+	return &watchableXmlSource{xmlSource{
+		name: path,
+		load: func() ([]byte, error) {
+			return ioutil.ReadFile(path)
+		},
+		version: func() (string, error) {
+			return fileVersion(os.Stat(path))
+		},
+	}}
+}
+
+// BytesSource 从内存中的xml数据加载配置
+func BytesSource(name string, data []byte) Source {
+	data = append([]byte(nil), data...)
+	return &xmlSource{
+		name: name,
+		load: func() ([]byte, error) {
+			return data, nil
+		},
+	}
+}
+
+// FSSource 从fs.FS(如embed.FS)中的xml文件加载配置
+func FSSource(fsys fs.FS, name string) Source {
+	return &watchableXmlSource{xmlSource{
+		name: name,
+		load: func() ([]byte, error) {
+			return fs.ReadFile(fsys, name)
+		},
+		version: func() (string, error) {
+			return fileVersion(fs.Stat(fsys, name))
+		},
+	}}
+}
+
+func fileVersion(fi fs.FileInfo, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", fi.ModTime().UnixNano(), fi.Size()), nil
+}
+
+// dirSource 目录下的多个xml文件
+type dirSource struct {
+	dir     string
+	pattern string
+}
+
+// DirSource 按文件名顺序加载目录下所有匹配pattern的xml文件, pattern为空时使用"*-site.xml"
+func DirSource(dir string, pattern string) WatchableSource {
+	if pattern == "" {
+		pattern = "*-site.xml"
+	}
+	return &dirSource{dir: dir, pattern: pattern}
+}
+
+// Name 实现Source接口
+func (s *dirSource) Name() string {
+	return s.dir
+}
+
+func (s *dirSource) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, s.pattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Load 实现Source接口, 各配置项的来源为其所在的文件
+func (s *dirSource) Load() ([]Property, error) {
+	docs, err := s.loadXmlFiles()
+	if err != nil {
+		return nil, err
+	}
+	var props []Property
+	for _, doc := range docs {
+		ps, err := parseProperties(doc.data, doc.name)
+		if err != nil {
+			return nil, err
+		}
+		for i := range ps {
+			ps[i].Source = doc.name
+		}
+		props = append(props, ps...)
+	}
+	return props, nil
+}
+
+func (s *dirSource) loadXmlFiles() ([]xmlDoc, error) {
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]xmlDoc, 0, len(files))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, xmlDoc{name: file, data: data})
+	}
+	return docs, nil
+}
+
+// Version 实现WatchableSource接口, 文件增删或修改都会改变版本
+func (s *dirSource) Version() (string, error) {
+	files, err := s.files()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, file := range files {
+		v, err := fileVersion(os.Stat(file))
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(file + ":" + v + ";")
+	}
+	return b.String(), nil
+}
+
+// envSource 环境变量
+type envSource struct {
+	prefix  string
+	mapping func(name string) string
+}
+
+// EnvSource 加载以prefix开头的环境变量, mapping将去掉前缀的变量名转换为key,
+// 为nil时使用DefaultEnvKeyMapping. mapping返回空字符串的变量会被忽略
+func EnvSource(prefix string, mapping func(name string) string) Source {
+	if mapping == nil {
+		mapping = DefaultEnvKeyMapping
+	}
+	return &envSource{prefix: prefix, mapping: mapping}
+}
+
+// DefaultEnvKeyMapping 将环境变量名转换为小写, "__"转换为"-", "_"转换为".",
+// 如DFS_NAMENODE_HTTP__ADDRESS转换为dfs.namenode.http-address
+func DefaultEnvKeyMapping(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "__", "-")
+	return strings.ReplaceAll(name, "_", ".")
+}
+
+// Name 实现Source接口
+func (s *envSource) Name() string {
+	return "env"
+}
+
+// Load 实现Source接口, 各配置项的来源为"env 变量名"
+func (s *envSource) Load() ([]Property, error) {
+	var props []Property
+	for _, kv := range os.Environ() {
+		i := strings.IndexByte(kv, '=')
+		if i <= 0 || !strings.HasPrefix(kv[:i], s.prefix) {
+			continue
+		}
+		key := s.mapping(kv[len(s.prefix):i])
+		if key == "" {
+			continue
+		}
+		props = append(props, Property{Name: key, RawValue: kv[i+1:], Source: "env " + kv[:i]})
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Source < props[j].Source })
+	return props, nil
+}
+
+// HTTPSource 通过HTTP(S) GET请求加载xml配置, client为nil时使用http.DefaultClient.
+// 版本取自ETag和Last-Modified响应头, 都不存在时使用响应内容的哈希
+func HTTPSource(url string, client *http.Client) WatchableSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpSource{url: url, client: client}
+}
+
+type httpSource struct {
+	url    string
+	client *http.Client
+}
+
+// Name 实现Source接口
+func (s *httpSource) Name() string {
+	return s.url
+}
+
+// Load 实现Source接口
+func (s *httpSource) Load() ([]Property, error) {
+	data, err := s.loadXml()
+	if err != nil {
+		return nil, err
+	}
+	return parseProperties(data, s.url)
+}
+
+func (s *httpSource) get(method string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, s.url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s %s: %s", method, s.url, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+func (s *httpSource) loadXml() ([]byte, error) {
+	_, data, err := s.get(http.MethodGet)
+	return data, err
+}
+
+// Version 实现WatchableSource接口
+func (s *httpSource) Version() (string, error) {
+	resp, _, err := s.get(http.MethodHead)
+	if err == nil {
+		if v := resp.Header.Get("ETag") + resp.Header.Get("Last-Modified"); v != "" {
+			return v, nil
+		}
+	}
+	_, data, err := s.get(http.MethodGet)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AddSource 按priority将配置来源加入资源栈并加载, priority大的来源覆盖priority小的来源,
+// priority相同时后加入的覆盖先加入的. 加入的位置不在栈顶时会重新加载整个资源栈
+func (x *XmlConfig) AddSource(src Source, priority int) error {
+	if src == nil {
+		return errors.New("nil source")
+	}
+	r := resource{src: src, priority: priority}
+	x.mu.RLock()
+	top := isTop(x.resources, priority)
+	x.mu.RUnlock()
+	if top {
+		// 加载期间有更高priority的来源加入时改为重新加载整个资源栈
+		if added, err := x.addResource(r); added || err != nil {
+			return err
+		}
+	}
+	e, err := x.reload(func(resources []resource) []resource {
+		i := sort.Search(len(resources), func(i int) bool { return resources[i].priority > priority })
+		return append(resources[:i:i], append([]resource{r}, resources[i:]...)...)
+	})
+	if err != nil {
+		return err
+	}
+	x.notify(e)
+	return nil
+}
+
+// isTop 判断priority的来源加入资源栈时是否位于栈顶
+func isTop(resources []resource, priority int) bool {
+	return len(resources) == 0 || resources[len(resources)-1].priority <= priority
+}
+
+// loaded 一个来源加载得到的内容, xml来源为文档数据, 其他来源为配置项
+type loaded struct {
+	isXml bool
+	docs  []xmlDoc
+	props []Property
+}
+
+// load 加载资源内容
+func (r *resource) load() (loaded, error) {
+	switch s := r.src.(type) {
+	case xmlDataSource:
+		data, err := s.loadXml()
+		return loaded{isXml: true, docs: []xmlDoc{{name: s.Name(), data: data}}}, err
+	case xmlFilesSource:
+		docs, err := s.loadXmlFiles()
+		return loaded{isXml: true, docs: docs}, err
+	}
+	props, err := r.src.Load()
+	return loaded{props: props}, err
+}
+
+// apply 将加载得到的内容应用到配置中
+func (x *XmlConfig) apply(l loaded, source string) error {
+	if l.isXml {
+		// 所有文档都解析成功后再应用, 任一文档出错时配置保持不变
+		parsed := make([]parsedXml, 0, len(l.docs))
+		for _, doc := range l.docs {
+			p, err := x.parse(doc.data, doc.name)
+			if err != nil {
+				return err
+			}
+			parsed = append(parsed, p)
+		}
+		x.finalSkipped = nil
+		for _, p := range parsed {
+			if p.doc != nil {
+				p.doc.layered = x.doc != nil
+				x.doc = p.doc
+			}
+			for _, prop := range p.props {
+				x.putProperties(prop, p.source)
+			}
+		}
+		return nil
+	}
+	x.finalSkipped = nil
+	for _, p := range l.props {
+		value := p.RawValue
+		if value == "" {
+			value = p.Value
+		}
+		src := p.Source
+		if src == "" {
+			src = source
+		}
+		x.putProperties(property{
+			XMLName:     xml.Name{Local: "property"},
+			Name:        p.Name,
+			Value:       value,
+			Tag:         strings.Join(p.Tags, ","),
+			Description: p.Description,
+			Final:       p.Final,
+		}, src)
+	}
+	return nil
+}
+
+// sourceVersions 返回资源栈中各可检测变化来源的版本
+func (x *XmlConfig) sourceVersions() []string {
+	x.mu.RLock()
+	resources := append([]resource(nil), x.resources...)
+	x.mu.RUnlock()
+	versions := make([]string, len(resources))
+	for i, r := range resources {
+		if s, ok := r.src.(WatchableSource); ok {
+			v, err := s.Version()
+			if err != nil {
+				v = "error: " + err.Error()
+			}
+			versions[i] = v
+		}
+	}
+	return versions
+}
@@ -0,0 +1,172 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSources(t *testing.T) {
+	dir := t.TempDir()
+	writeTempXml(t, dir, "core-site.xml", coreSite)
+	writeTempXml(t, dir, "hdfs-site.xml", `<configuration><property><name>dfs.replication</name><value>2</value></property></configuration>`)
+	writeTempXml(t, dir, "ignored.xml", `<configuration><property><name>ignored</name><value>1</value></property></configuration>`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(coreSite))
+	}))
+	defer server.Close()
+	os.Setenv("XMLCONFIG_TEST_DFS_NAMENODE_HTTP__ADDRESS", "nn:9870")
+	defer os.Unsetenv("XMLCONFIG_TEST_DFS_NAMENODE_HTTP__ADDRESS")
+
+	tests := []struct {
+		name       string
+		src        Source
+		key        string
+		wantValue  string
+		wantSource string
+	}{
+		{
+			name:       "文件",
+			src:        FileSource(dir + "/core-site.xml"),
+			key:        "fs.defaultFS",
+			wantValue:  "hdfs://nn:8020",
+			wantSource: dir + "/core-site.xml",
+		},
+		{
+			name:       "内存",
+			src:        BytesSource("bytes", []byte(coreSite)),
+			key:        "io.file.buffer.size",
+			wantValue:  "65536",
+			wantSource: "bytes",
+		},
+		{
+			name:       "fs.FS",
+			src:        FSSource(fstest.MapFS{"conf/core-site.xml": {Data: []byte(coreSite)}}, "conf/core-site.xml"),
+			key:        "fs.defaultFS",
+			wantValue:  "hdfs://nn:8020",
+			wantSource: "conf/core-site.xml",
+		},
+		{
+			name:       "目录",
+			src:        DirSource(dir, ""),
+			key:        "dfs.replication",
+			wantValue:  "2",
+			wantSource: dir + "/hdfs-site.xml",
+		},
+		{
+			name:       "环境变量",
+			src:        EnvSource("XMLCONFIG_TEST_", nil),
+			key:        "dfs.namenode.http-address",
+			wantValue:  "nn:9870",
+			wantSource: "env XMLCONFIG_TEST_DFS_NAMENODE_HTTP__ADDRESS",
+		},
+		{
+			name:       "HTTP",
+			src:        HTTPSource(server.URL, nil),
+			key:        "fs.defaultFS",
+			wantValue:  "hdfs://nn:8020",
+			wantSource: server.URL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewXmlConfig()
+			assert.Nil(t, x.AddSource(tt.src, 0))
+			p, ok := x.Lookup(tt.key)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantValue, p.Value)
+			assert.Equal(t, tt.wantSource, p.Source)
+			assert.Equal(t, []string{tt.src.Name()}, x.GetResources())
+		})
+	}
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddSource(DirSource(dir, ""), 0))
+	assert.Equal(t, "", x.GetString("ignored", ""))
+	assert.NotNil(t, x.AddSource(FileSource(dir+"/not-exist.xml"), 0))
+
+	// 没有匹配的环境变量时不加入任何配置, 重新加载也不出错
+	empty := NewXmlConfig()
+	assert.Nil(t, empty.AddResourceData("core-site.xml", []byte(coreSite)))
+	assert.Nil(t, empty.AddSource(EnvSource("XMLCONFIG_NO_MATCH_", nil), 10))
+	assert.Nil(t, empty.AddSource(EnvSource("XMLCONFIG_NO_MATCH_", nil), -1))
+	assert.Nil(t, empty.Reload())
+	assert.Equal(t, []string{"env", "core-site.xml", "env"}, empty.GetResources())
+	assert.Equal(t, "hdfs://nn:8020", empty.GetString("fs.defaultFS", ""))
+	assert.NotNil(t, x.AddSource(HTTPSource(server.URL+"/missing", server.Client()), 0))
+}
+
+func TestXmlConfig_AddSource_Priority(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddSource(BytesSource("override", []byte(`<configuration><property><name>fs.defaultFS</name><value>hdfs://override</value></property></configuration>`)), 10))
+	assert.Nil(t, x.AddSource(BytesSource("core-site.xml", []byte(coreSite)), 1))
+	assert.Nil(t, x.AddSource(BytesSource("core-default.xml", []byte(coreDefault)), 0))
+	assert.Equal(t, []string{"core-default.xml", "core-site.xml", "override"}, x.GetResources())
+	assert.Equal(t, "hdfs://override", x.GetString("fs.defaultFS", ""))
+	assert.Equal(t, []string{"core-default.xml", "core-site.xml", "override"}, x.GetPropertySources("fs.defaultFS"))
+	assert.Equal(t, "4096", x.GetString("io.file.buffer.size", ""))
+
+	assert.NotNil(t, x.AddSource(BytesSource("broken", []byte("<configuration>")), 0))
+	assert.Equal(t, []string{"core-default.xml", "core-site.xml", "override"}, x.GetResources())
+	assert.NotNil(t, x.AddSource(nil, 0))
+}
+
+func TestXmlConfig_Watch_HTTPSource(t *testing.T) {
+	var version int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.LoadInt32(&version)
+		w.Header().Set("ETag", string(rune('0'+v)))
+		if r.Method == http.MethodHead {
+			return
+		}
+		if v == 0 {
+			w.Write([]byte(coreSite))
+		} else {
+			w.Write([]byte(coreDefault))
+		}
+	}))
+	defer server.Close()
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddSource(HTTPSource(server.URL, server.Client()), 0))
+	events := make(chan ChangeEvent, 10)
+	defer x.Subscribe(func(e ChangeEvent) { events <- e })()
+	defer x.Watch(5 * time.Millisecond)()
+	atomic.StoreInt32(&version, 1)
+	select {
+	case e := <-events:
+		assert.Nil(t, e.Err)
+		assert.Equal(t, "file:///", x.GetString("fs.defaultFS", ""))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change event")
+	}
+}
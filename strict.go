@@ -0,0 +1,163 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrInvalidRoot 根元素不是<configuration>
+	ErrInvalidRoot = errors.New("invalid root element")
+	// ErrUnknownElement 严格模式下出现了未知的元素
+	ErrUnknownElement = errors.New("unknown element")
+	// ErrMissingName 严格模式下配置项缺少<name>或name为空
+	ErrMissingName = errors.New("missing property name")
+	// ErrMissingValue 严格模式下配置项缺少<value>
+	ErrMissingValue = errors.New("missing property value")
+	// ErrDuplicateProperty 严格模式下同一文档中重复定义了配置项
+	ErrDuplicateProperty = errors.New("duplicate property")
+)
+
+// DuplicateError 重复定义的配置项, Line和Column为第一次定义的位置
+type DuplicateError struct {
+	Line   int
+	Column int
+}
+
+// Error 实现error接口
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("%v, first defined at %d:%d", ErrDuplicateProperty, e.Line, e.Column)
+}
+
+// Is 使errors.Is(err, ErrDuplicateProperty)成立
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicateProperty
+}
+
+// ValidationError 严格模式下发现的所有问题, 按在文档中出现的顺序排列
+type ValidationError struct {
+	Problems []*ParseError
+}
+
+// Error 实现error接口, 每行一个问题
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		lines = append(lines, p.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Is 任一问题匹配target时返回true
+func (e *ValidationError) Is(target error) bool {
+	for _, p := range e.Problems {
+		if errors.Is(p, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStrict 设置严格模式, 开启后解析xml配置时会检查重复的配置项、缺少name或value的配置项、
+// 未知的元素和错误的根元素, 有问题时返回包含所有问题的*ValidationError且配置保持不变
+func (x *XmlConfig) SetStrict(strict bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.strict = strict
+}
+
+// Validate 以严格模式检查r中的xml配置, 有问题时返回包含所有问题的*ValidationError,
+// source为问题中报告的资源名称
+func Validate(r io.Reader, source string) error {
+	_, err := decodeXml(r, source, true)
+	return err
+}
+
+// ValidateFile 以严格模式检查xml配置文件, 可用于提交前检查site文件
+func ValidateFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Validate(f, path)
+}
+
+// strictProperty 严格模式下解码的配置项, 用于区分缺失的子元素并收集未知的子元素
+type strictProperty struct {
+	Name        *string  `xml:"name"`
+	Value       *string  `xml:"value"`
+	Tag         string   `xml:"tag"`
+	Description string   `xml:"description"`
+	Final       bool     `xml:"final"`
+	Source      []string `xml:"source"`
+	Unknown     []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// decodeStrict 解码start开始的配置项并记录其中的问题, off为配置项在文档中的偏移
+func (dec *xmlDecoder) decodeStrict(p *property, start *xml.StartElement, off int64) error {
+	var sp strictProperty
+	err := dec.d.DecodeElement(&sp, start)
+	if sp.Name != nil {
+		p.Name = *sp.Name
+	}
+	if err != nil {
+		return err
+	}
+	p.XMLName = start.Name
+	if sp.Value != nil {
+		p.Value = *sp.Value
+	}
+	p.Tag, p.Description, p.Final = sp.Tag, sp.Description, sp.Final
+
+	pos := dec.pr.position(off)
+	problem := func(err error) {
+		dec.problems = append(dec.problems, &ParseError{
+			Source: dec.source, Line: pos.line, Column: pos.column, Property: p.Name, Err: err,
+		})
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		problem(ErrMissingName)
+	} else if first, ok := dec.seen[p.Name]; ok {
+		problem(&DuplicateError{Line: first.line, Column: first.column})
+	} else {
+		if dec.seen == nil {
+			dec.seen = make(map[string]position)
+		}
+		dec.seen[p.Name] = pos
+	}
+	if sp.Value == nil {
+		problem(ErrMissingValue)
+	}
+	for _, u := range sp.Unknown {
+		problem(fmt.Errorf("%w <%s> in <property>", ErrUnknownElement, u.XMLName.Local))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const malformedSite = `<?xml version="1.0"?>
+<configuration>
+  <property>
+    <name>dfs.replication</name>
+    <value>3</value>
+  </property>
+  <property>
+    <value>orphan</value>
+  </property>
+  <property>
+    <name>dfs.blocksize</name>
+    <description>no value</description>
+  </property>
+  <property>
+    <name>dfs.replication</name>
+    <value>2</value>
+    <owner>hdfs</owner>
+  </property>
+  <include/>
+</configuration>`
+
+func TestValidate(t *testing.T) {
+	err := Validate(strings.NewReader(malformedSite), "hdfs-site.xml")
+	var ve *ValidationError
+	if !assert.True(t, errors.As(err, &ve), "%v", err) {
+		return
+	}
+	type problem struct {
+		line     int
+		property string
+		err      error
+	}
+	want := []problem{
+		{7, "", ErrMissingName},
+		{10, "dfs.blocksize", ErrMissingValue},
+		{14, "dfs.replication", ErrDuplicateProperty},
+		{14, "dfs.replication", ErrUnknownElement},
+		{19, "", ErrUnknownElement},
+	}
+	if !assert.Len(t, ve.Problems, len(want), err.Error()) {
+		return
+	}
+	for i, w := range want {
+		p := ve.Problems[i]
+		assert.Equal(t, "hdfs-site.xml", p.Source)
+		assert.Equal(t, w.line, p.Line, p.Error())
+		assert.Equal(t, w.property, p.Property, p.Error())
+		assert.True(t, errors.Is(p, w.err), p.Error())
+	}
+	var dup *DuplicateError
+	assert.True(t, errors.As(ve.Problems[2], &dup))
+	assert.Equal(t, 3, dup.Line)
+	assert.Equal(t, 3, dup.Column)
+	assert.Contains(t, err.Error(), "hdfs-site.xml:14:3: property dfs.replication: duplicate property, first defined at 3:3")
+	assert.True(t, errors.Is(err, ErrDuplicateProperty))
+	assert.False(t, errors.Is(err, ErrInvalidRoot))
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr []error
+	}{
+		{name: "合法的配置", data: stringCase},
+		{name: "空值", data: "<configuration><property><name>a</name><value/></property></configuration>"},
+		{name: "错误的根元素", data: "<properties><property><name>a</name><value>1</value></property></properties>", wantErr: []error{ErrInvalidRoot}},
+		{name: "语法错误", data: "<configuration><property><name>a</name></property><oops></configuration>", wantErr: []error{ErrMissingValue, ErrUnknownElement, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(strings.NewReader(tt.data), "")
+			if tt.wantErr == nil {
+				assert.Nil(t, err)
+				return
+			}
+			var ve *ValidationError
+			if !assert.True(t, errors.As(err, &ve), "%v", err) {
+				return
+			}
+			assert.Len(t, ve.Problems, len(tt.wantErr))
+			for i, w := range tt.wantErr {
+				if w != nil {
+					assert.True(t, errors.Is(ve.Problems[i], w), ve.Problems[i].Error())
+				}
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	writeTempXml(t, dir, "hdfs-site.xml", malformedSite)
+	err = ValidateFile(dir + "/hdfs-site.xml")
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, dir+"/hdfs-site.xml", ve.Problems[0].Source)
+	assert.NotNil(t, ValidateFile(dir+"/not-exist.xml"))
+}
+
+func TestXmlConfig_SetStrict(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddResourceData("lenient", []byte(malformedSite)))
+	assert.Equal(t, "2", x.GetString("dfs.replication", ""))
+
+	x = NewXmlConfig()
+	x.SetStrict(true)
+	assert.Nil(t, x.AddResourceData("core-site.xml", []byte(coreSite)))
+	err := x.AddResourceData("hdfs-site.xml", []byte(malformedSite))
+	assert.True(t, errors.Is(err, ErrDuplicateProperty))
+	assert.Equal(t, "", x.GetString("dfs.replication", ""))
+	assert.Equal(t, []string{"core-site.xml"}, x.GetResources())
+	assert.True(t, errors.Is(x.ReadXml(strings.NewReader(malformedSite)), ErrMissingName))
+	assert.Nil(t, x.Reload())
+
+	x.SetStrict(false)
+	assert.Nil(t, x.AddResourceData("hdfs-site.xml", []byte(malformedSite)))
+	assert.Equal(t, "2", x.GetString("dfs.replication", ""))
+}
+
+func TestXmlConfig_SetStrict_DirSource(t *testing.T) {
+	dir := t.TempDir()
+	writeTempXml(t, dir, "core-site.xml", coreSite)
+	writeTempXml(t, dir, "hdfs-site.xml", malformedSite)
+
+	x := NewXmlConfig()
+	assert.Nil(t, x.AddSource(DirSource(dir, ""), 0))
+	assert.Equal(t, "2", x.GetString("dfs.replication", ""))
+	p, _ := x.Lookup("dfs.replication")
+	assert.Equal(t, dir+"/hdfs-site.xml", p.Source)
+
+	x = NewXmlConfig()
+	x.SetStrict(true)
+	err := x.AddSource(DirSource(dir, ""), 0)
+	assert.True(t, errors.Is(err, ErrDuplicateProperty))
+	var ve *ValidationError
+	if assert.ErrorAs(t, err, &ve) {
+		assert.Equal(t, dir+"/hdfs-site.xml", ve.Problems[0].Source)
+	}
+	// 任一文件出错时其他文件也不会被应用
+	assert.Equal(t, "", x.GetString("fs.defaultFS", ""))
+	assert.Empty(t, x.GetResources())
+}
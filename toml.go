@@ -0,0 +1,549 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// BuildTomlData 将配置构建为TOML, 每个配置为一个顶层键, 包含.的key加引号以免被解析为表,
+// 值均为字符串, 描述作为键前的#注释
+func (x *XmlConfig) BuildTomlData() ([]byte, error) {
+	var b bytes.Buffer
+	for _, p := range x.Properties() {
+		for _, line := range descriptionLines(p.Description) {
+			b.WriteString("# ")
+			b.WriteString(strings.Map(func(r rune) rune {
+				if r < 0x20 && r != '\t' || r == 0x7f {
+					return ' '
+				}
+				return r
+			}, line))
+			b.WriteString("\n")
+		}
+		b.WriteString(tomlKey(p.Name))
+		b.WriteString(" = ")
+		b.WriteString(tomlString(p.RawValue))
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
+
+// ParseTomlData 解析TOML配置, 与ParseXmlData一样不加入资源栈. 表和点分隔的键以.连接为key, 数组以逗号连接为值,
+// 内联表展开为多个key, 键前的注释作为描述. 只支持TOML的子集: 值可以是字符串、整数、浮点数、布尔值
+// 及由它们组成的数组, 十六进制、八进制和二进制整数转换为十进制. 不支持表数组和日期时间(可以使用加引号的字符串),
+// 不检查重复定义的key. 解析失败时返回*ParseError
+func (x *XmlConfig) ParseTomlData(data []byte) error {
+	props, err := decodeToml(data)
+	if err != nil {
+		return err
+	}
+	x.applyOnce(props, nil)
+	return nil
+}
+
+// isBareKey 判断key是否可以不加引号
+func isBareKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if !isBareKeyChar(key[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func tomlKey(key string) string {
+	if isBareKey(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString 生成TOML的基本字符串
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlParser 解析TOML中本库需要的子集
+type tomlParser struct {
+	s        string
+	pos      int
+	line     int
+	props    []Property
+	comments []string
+}
+
+// decodeToml 解析TOML
+func decodeToml(data []byte) ([]Property, error) {
+	p := &tomlParser{s: string(data), line: 1, props: make([]Property, 0)}
+	if err := p.parse(); err != nil {
+		return nil, &ParseError{Line: p.line, Column: p.column(), Err: err}
+	}
+	return p.props, nil
+}
+
+func (p *tomlParser) column() int {
+	start := strings.LastIndexByte(p.s[:p.pos], '\n') + 1
+	return utf8.RuneCountInString(p.s[start:p.pos]) + 1
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *tomlParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipSpacesAndNewlines 跳过空白、换行和注释, 用于数组内部
+func (p *tomlParser) skipSpacesAndNewlines() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.comment()
+		default:
+			return
+		}
+	}
+}
+
+// comment 读取到行尾的注释并返回去掉#后的内容
+func (p *tomlParser) comment() string {
+	end := strings.IndexByte(p.s[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.s) - p.pos
+	}
+	c := p.s[p.pos+1 : p.pos+end]
+	p.pos += end
+	return strings.TrimSpace(c)
+}
+
+// endOfLine 读取行尾的空白和注释以及换行
+func (p *tomlParser) endOfLine() error {
+	p.skipSpaces()
+	if p.peek() == '#' {
+		p.comment()
+	}
+	if p.peek() == '\r' {
+		p.pos++
+	}
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != '\n' {
+		return fmt.Errorf("unexpected %q after value", p.peek())
+	}
+	p.pos++
+	p.line++
+	return nil
+}
+
+func (p *tomlParser) parse() error {
+	prefix := ""
+	for {
+		p.skipSpaces()
+		if p.eof() {
+			return nil
+		}
+		switch c := p.peek(); c {
+		case '#':
+			p.comments = append(p.comments, p.comment())
+			if err := p.endOfLine(); err != nil {
+				return err
+			}
+		case '\r', '\n':
+			// 空行之前的注释不属于任何键
+			p.comments = nil
+			if err := p.endOfLine(); err != nil {
+				return err
+			}
+		case '[':
+			p.pos++
+			if p.peek() == '[' {
+				return errors.New("arrays of tables are not supported")
+			}
+			p.skipSpaces()
+			key, err := p.key()
+			if err != nil {
+				return err
+			}
+			p.skipSpaces()
+			if p.peek() != ']' {
+				return errors.New("expected ] after table name")
+			}
+			p.pos++
+			prefix = key + "."
+			p.comments = nil
+			if err := p.endOfLine(); err != nil {
+				return err
+			}
+		default:
+			key, err := p.key()
+			if err != nil {
+				return err
+			}
+			if err := p.keyValue(prefix + key); err != nil {
+				return err
+			}
+			if err := p.endOfLine(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// keyValue 读取=及之后的值, 内联表展开为多个配置项
+func (p *tomlParser) keyValue(key string) error {
+	p.skipSpaces()
+	if p.peek() != '=' {
+		return fmt.Errorf("expected = after key %s", key)
+	}
+	p.pos++
+	p.skipSpaces()
+	description := strings.Join(p.comments, "\n")
+	p.comments = nil
+	if p.peek() == '{' {
+		return p.inlineTable(key)
+	}
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	p.props = append(p.props, Property{Name: key, Value: value, RawValue: value, Description: description})
+	return nil
+}
+
+// key 读取以.连接的键, 各部分可以是裸键、基本字符串或字面字符串
+func (p *tomlParser) key() (string, error) {
+	var parts []string
+	for {
+		var part string
+		var err error
+		switch c := p.peek(); {
+		case c == '"':
+			part, err = p.basicString()
+		case c == '\'':
+			part, err = p.literalString()
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return "", fmt.Errorf("invalid key character %q", c)
+			}
+			part = p.s[start:p.pos]
+		}
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+		p.skipSpaces()
+		if p.peek() != '.' {
+			return strings.Join(parts, "."), nil
+		}
+		p.pos++
+		p.skipSpaces()
+	}
+}
+
+// inlineTable 读取内联表并将其中的键展开为prefix.key
+func (p *tomlParser) inlineTable(prefix string) error {
+	p.pos++
+	p.skipSpaces()
+	if p.peek() == '}' {
+		p.pos++
+		return nil
+	}
+	for {
+		key, err := p.key()
+		if err != nil {
+			return err
+		}
+		if err := p.keyValue(prefix + "." + key); err != nil {
+			return err
+		}
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+			p.skipSpaces()
+		case '}':
+			p.pos++
+			return nil
+		default:
+			return errors.New("expected , or } in inline table")
+		}
+	}
+}
+
+// tomlNumber 匹配TOML的整数(十进制、0x、0o、0b)、浮点数及inf和nan
+var tomlNumber = regexp.MustCompile(`^(?:[+-]?(?:0|[1-9](?:_?[0-9])*)(?:\.[0-9](?:_?[0-9])*)?(?:[eE][+-]?[0-9](?:_?[0-9])*)?|0x[0-9A-Fa-f](?:_?[0-9A-Fa-f])*|0o[0-7](?:_?[0-7])*|0b[01](?:_?[01])*|[+-]?(?:inf|nan))$`)
+
+// value 读取字符串、数组、数字或布尔值, 数字中的下划线会被去除
+func (p *tomlParser) value() (string, error) {
+	switch {
+	case strings.HasPrefix(p.s[p.pos:], `"""`):
+		return p.multilineString(`"""`)
+	case strings.HasPrefix(p.s[p.pos:], `'''`):
+		return p.multilineString(`'''`)
+	case p.peek() == '"':
+		return p.basicString()
+	case p.peek() == '\'':
+		return p.literalString()
+	case p.peek() == '[':
+		return p.array()
+	}
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	v := p.s[start:p.pos]
+	switch {
+	case v == "":
+		return "", errors.New("missing value")
+	case v == "true" || v == "false":
+		return v, nil
+	case tomlNumber.MatchString(v) && len(v) > 2 && v[0] == '0' && strings.ContainsRune("xob", rune(v[1])):
+		// 十六进制、八进制和二进制整数转换为十进制, 以便GetInt读取
+		n, err := strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid integer %q: %w", v, err)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case tomlNumber.MatchString(v):
+		return strings.ReplaceAll(v, "_", ""), nil
+	}
+	p.pos = start
+	return "", fmt.Errorf("unsupported value %q, strings must be quoted and dates are not supported", v)
+}
+
+// array 读取数组并以逗号连接其中的元素
+func (p *tomlParser) array() (string, error) {
+	p.pos++
+	var items []string
+	for {
+		p.skipSpacesAndNewlines()
+		if p.peek() == ']' {
+			p.pos++
+			return strings.Join(items, ","), nil
+		}
+		if p.peek() == '[' || p.peek() == '{' {
+			return "", errors.New("nested arrays and tables in arrays are not supported")
+		}
+		item, err := p.value()
+		if err != nil {
+			return "", err
+		}
+		items = append(items, item)
+		p.skipSpacesAndNewlines()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return "", errors.New("expected , or ] in array")
+		}
+	}
+}
+
+// literalString 读取单引号字符串
+func (p *tomlParser) literalString() (string, error) {
+	end := strings.IndexAny(p.s[p.pos+1:], "'\n")
+	if end < 0 || p.s[p.pos+1+end] != '\'' {
+		return "", errors.New("unterminated literal string")
+	}
+	v := p.s[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return v, nil
+}
+
+// basicString 读取双引号字符串并还原转义
+func (p *tomlParser) basicString() (string, error) {
+	var b strings.Builder
+	p.pos++
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", errors.New("unterminated string")
+		}
+		c := p.peek()
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// multilineString 读取三引号字符串, 紧跟开头引号的换行会被去除
+func (p *tomlParser) multilineString(quote string) (string, error) {
+	p.pos += len(quote)
+	if strings.HasPrefix(p.s[p.pos:], "\r\n") {
+		p.pos += 2
+		p.line++
+	} else if p.peek() == '\n' {
+		p.pos++
+		p.line++
+	}
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", errors.New("unterminated multi-line string")
+		}
+		if strings.HasPrefix(p.s[p.pos:], quote) {
+			p.pos += len(quote)
+			// 结束引号前最多可以有两个引号属于字符串内容
+			for i := 0; i < 2 && strings.HasPrefix(p.s[p.pos:], quote[:1]); i++ {
+				b.WriteByte(quote[0])
+				p.pos++
+			}
+			return b.String(), nil
+		}
+		c := p.peek()
+		p.pos++
+		switch {
+		case c == '\n':
+			p.line++
+			b.WriteByte(c)
+		case c == '\\' && quote == `"""`:
+			// 行尾的反斜杠去除换行和下一行开头的空白
+			rest := strings.TrimLeft(p.s[p.pos:], " \t\r")
+			if strings.HasPrefix(rest, "\n") {
+				p.pos = len(p.s) - len(rest)
+				for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+					if p.peek() == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				continue
+			}
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// escape 还原反斜杠之后的转义
+func (p *tomlParser) escape(b *strings.Builder) error {
+	if p.eof() {
+		return errors.New("unterminated escape")
+	}
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.s) {
+			return errors.New("invalid unicode escape")
+		}
+		r, err := strconv.ParseUint(p.s[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return fmt.Errorf("invalid unicode escape \\%c%s", c, p.s[p.pos:p.pos+n])
+		}
+		b.WriteRune(rune(r))
+		p.pos += n
+	default:
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXmlConfig_BuildTomlData(t *testing.T) {
+	x := NewXmlConfig()
+	x.SetStringWithDescription("dfs.replication", "3", "Default block\nreplication.")
+	x.SetString("plain_key", "a \"quoted\" \\ value\n\x01")
+	x.SetString("unicode", "中文")
+	data, err := x.BuildTomlData()
+	assert.Nil(t, err)
+	assert.Equal(t, `# Default block
+# replication.
+"dfs.replication" = "3"
+plain_key = "a \"quoted\" \\ value\n\u0001"
+unicode = "中文"
+`, string(data))
+
+	live := NewXmlConfig()
+	assert.Nil(t, live.ParseTomlData(data))
+	assert.Equal(t, x.GetConfigKeys(), live.GetConfigKeys())
+	assert.True(t, Diff(x, live).Empty())
+}
+
+func TestXmlConfig_ParseTomlData(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.ParseTomlData([]byte(`# file header
+
+# replication factor
+dfs.replication = 3
+"fs.defaultFS" = 'hdfs://nn:8020' # trailing comment
+size = 1_048_576
+enabled = true
+ratio = -1.5e3
+mask = 0xFF
+mode = 0o7_55
+flags = 0b1010
+started = "1979-05-27T07:32:00Z"
+
+[yarn."resourcemanager"]
+hosts = [
+  "rm1", # first
+  'rm2',
+]
+address = { host = "rm", port = 8032 }
+script = """
+echo \
+  hello\tworld"""
+raw = '''C:\path'''
+`)))
+	assert.Equal(t, map[string]string{
+		"dfs.replication":                   "3",
+		"fs.defaultFS":                      "hdfs://nn:8020",
+		"size":                              "1048576",
+		"enabled":                           "true",
+		"ratio":                             "-1.5e3",
+		"mask":                              "255",
+		"mode":                              "493",
+		"flags":                             "10",
+		"started":                           "1979-05-27T07:32:00Z",
+		"yarn.resourcemanager.hosts":        "rm1,rm2",
+		"yarn.resourcemanager.address.host": "rm",
+		"yarn.resourcemanager.address.port": "8032",
+		"yarn.resourcemanager.script":       "echo hello\tworld",
+		"yarn.resourcemanager.raw":          `C:\path`,
+	}, x.Snapshot())
+	mask, err := x.GetInt("mask", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 255, mask)
+	p, ok := x.Lookup("dfs.replication")
+	assert.True(t, ok)
+	assert.Equal(t, "replication factor", p.Description)
+	p, _ = x.Lookup("fs.defaultFS")
+	assert.Equal(t, "", p.Description)
+}
+
+func TestXmlConfig_ParseTomlDataError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		line int
+	}{
+		{name: "array of tables", data: "a = 1\n[[servers]]\n", line: 2},
+		{name: "missing equals", data: "a 1\n", line: 1},
+		{name: "unterminated string", data: "a = \"x\nb = 1\n", line: 1},
+		{name: "invalid escape", data: "a = 1\nb = \"\\x\"\n", line: 2},
+		{name: "trailing garbage", data: "a = 1 2\n", line: 1},
+		{name: "unterminated multi-line", data: "a = \"\"\"\nx\n", line: 3},
+		{name: "unquoted string", data: "a = 1\nb = hello\n", line: 2},
+		{name: "date", data: "a = 1979-05-27T07:32:00Z\n", line: 1},
+		{name: "unquoted string in array", data: "a = [1,\n  two]\n", line: 2},
+		{name: "leading zero", data: "a = 007\n", line: 1},
+		{name: "capitalized bool", data: "a = True\n", line: 1},
+		{name: "hex out of range", data: "a = 1\nb = 0xFFFFFFFFFFFFFFFF\n", line: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewXmlConfig()
+			err := x.ParseTomlData([]byte(tt.data))
+			var pe *ParseError
+			if assert.ErrorAs(t, err, &pe) {
+				assert.Equal(t, tt.line, pe.Line)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnits hadoop支持的时间单位后缀, 按匹配优先级排列
+var durationUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"ns", time.Nanosecond},
+	{"us", time.Microsecond},
+	{"µs", time.Microsecond},
+	{"ms", time.Millisecond},
+	{"s", time.Second},
+	{"m", time.Minute},
+	{"h", time.Hour},
+	{"d", 24 * time.Hour},
+}
+
+// parseDuration 解析带有ns、us、ms、s、m、h、d后缀的时间, 没有后缀时以defaultUnit为单位
+func parseDuration(s string, defaultUnit time.Duration) (time.Duration, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	unit := defaultUnit
+	for _, u := range durationUnits {
+		if strings.HasSuffix(str, u.suffix) {
+			str, unit = strings.TrimSpace(str[:len(str)-len(u.suffix)]), u.unit
+			break
+		}
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok {
+			err = ne.Err
+		}
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	if unit <= 0 {
+		return 0, fmt.Errorf("invalid duration unit %d", unit)
+	}
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, strconv.ErrRange)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// formatDuration 以能精确表示d的最大单位输出, 如30s、5m、200ms
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0"
+	}
+	for i := len(durationUnits) - 1; i >= 0; i-- {
+		u := durationUnits[i]
+		if u.suffix != "µs" && d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(d), 10) + "ns"
+}
+
+// StorageUnit 存储容量单位, 值为该单位对应的字节数
+type StorageUnit int64
+
+// 二进制单位, 与hadoop的k、m、g等后缀一致
+const (
+	Byte StorageUnit = 1
+	KB   StorageUnit = 1 << (10 * iota)
+	MB
+	GB
+	TB
+	PB
+	EB
+)
+
+// 十进制单位, 仅用于换算结果
+const (
+	DecimalKB StorageUnit = 1000
+	DecimalMB             = DecimalKB * 1000
+	DecimalGB             = DecimalMB * 1000
+	DecimalTB             = DecimalGB * 1000
+	DecimalPB             = DecimalTB * 1000
+	DecimalEB             = DecimalPB * 1000
+)
+
+// storageUnits hadoop支持的容量单位前缀
+var storageUnits = []struct {
+	prefix byte
+	unit   StorageUnit
+}{
+	{'e', EB},
+	{'p', PB},
+	{'t', TB},
+	{'g', GB},
+	{'m', MB},
+	{'k', KB},
+}
+
+// splitStorageSize 拆分容量中的数字和单位, 后缀不区分大小写, 支持k、kb、kib等形式, 均按1024进制换算.
+// 没有后缀时以defaultUnit为单位
+func splitStorageSize(s string, defaultUnit StorageUnit) (string, StorageUnit) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	hasB := false
+	if strings.HasSuffix(str, "ib") {
+		str, hasB = str[:len(str)-2], true
+	} else if strings.HasSuffix(str, "b") {
+		str, hasB = str[:len(str)-1], true
+	}
+	if str != "" {
+		for _, u := range storageUnits {
+			if str[len(str)-1] == u.prefix {
+				return strings.TrimSpace(str[:len(str)-1]), u.unit
+			}
+		}
+	}
+	if hasB {
+		return strings.TrimSpace(str), Byte
+	}
+	return str, defaultUnit
+}
+
+// parseBytes 解析容量并转换为字节数, 支持1.5g这样的小数, 结果向最近的整数取整
+func parseBytes(s string, defaultUnit StorageUnit) (int64, error) {
+	num, unit := splitStorageSize(s, defaultUnit)
+	if !strings.ContainsAny(num, ".eE") {
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			if ne, ok := err.(*strconv.NumError); ok {
+				err = ne.Err
+			}
+			return 0, fmt.Errorf("invalid storage size %q: %w", s, err)
+		}
+		if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+			return 0, fmt.Errorf("invalid storage size %q: %w", s, strconv.ErrRange)
+		}
+		return n * int64(unit), nil
+	}
+	f, err := parseStorageSize(s, defaultUnit)
+	if err != nil {
+		return 0, err
+	}
+	f = math.Round(f)
+	if f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("invalid storage size %q: %w", s, strconv.ErrRange)
+	}
+	return int64(f), nil
+}
+
+// parseStorageSize 解析容量并转换为字节数
+func parseStorageSize(s string, defaultUnit StorageUnit) (float64, error) {
+	num, unit := splitStorageSize(s, defaultUnit)
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok {
+			err = ne.Err
+		}
+		return 0, fmt.Errorf("invalid storage size %q: %w", s, err)
+	}
+	f *= float64(unit)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid storage size %q: %w", s, strconv.ErrRange)
+	}
+	return f, nil
+}
+
+// formatBytes 以能精确表示n的最大单位输出, 如128m、64k、1000
+func formatBytes(n int64) string {
+	for _, u := range storageUnits {
+		if n != 0 && n%int64(u.unit) == 0 {
+			return strconv.FormatInt(n/int64(u.unit), 10) + string(u.prefix)
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
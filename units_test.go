@@ -0,0 +1,151 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s       string
+		unit    time.Duration
+		want    time.Duration
+		wantErr error
+	}{
+		{"30s", time.Millisecond, 30 * time.Second, nil},
+		{" 5m ", time.Millisecond, 5 * time.Minute, nil},
+		{"1H", time.Millisecond, time.Hour, nil},
+		{"200ms", time.Second, 200 * time.Millisecond, nil},
+		{"2d", time.Second, 48 * time.Hour, nil},
+		{"7us", time.Second, 7 * time.Microsecond, nil},
+		{"9ns", time.Second, 9, nil},
+		{"-3 s", time.Millisecond, -3 * time.Second, nil},
+		{"1500", time.Millisecond, 1500 * time.Millisecond, nil},
+		{"3", time.Second, 3 * time.Second, nil},
+		{"abc", time.Second, 0, strconv.ErrSyntax},
+		{"1.5s", time.Second, 0, strconv.ErrSyntax},
+		{"", time.Second, 0, strconv.ErrSyntax},
+		{"106752d", time.Second, 0, strconv.ErrRange},
+		{"9223372036854775808", time.Nanosecond, 0, strconv.ErrRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			d, err := parseDuration(tt.s, tt.unit)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "parseDuration(%v): %v", tt.s, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, d)
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0"},
+		{30 * time.Second, "30s"},
+		{90 * time.Second, "90s"},
+		{5 * time.Minute, "5m"},
+		{48 * time.Hour, "2d"},
+		{200 * time.Millisecond, "200ms"},
+		{1500 * time.Microsecond, "1500us"},
+		{-time.Hour, "-1h"},
+		{7, "7ns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatDuration(tt.d))
+			d, err := parseDuration(tt.want, time.Nanosecond)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.d, d)
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr error
+	}{
+		{"128m", 128 << 20, nil},
+		{"1g", 1 << 30, nil},
+		{"64KB", 64 << 10, nil},
+		{"2TB", 2 << 40, nil},
+		{"4 MiB", 4 << 20, nil},
+		{"1.5g", 3 << 29, nil},
+		{"512b", 512, nil},
+		{"1e", 1 << 60, nil},
+		{"100", 100, nil},
+		{"7E", 7 << 60, nil},
+		{"8e", 0, strconv.ErrRange},
+		{"9223372036854775807", 9223372036854775807, nil},
+		{"8192p", 0, strconv.ErrRange},
+		{"1.5", 2, nil},
+		{"1x", 0, strconv.ErrSyntax},
+		{"kb", 0, strconv.ErrSyntax},
+		{"", 0, strconv.ErrSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			n, err := parseBytes(tt.s, Byte)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "parseBytes(%v): %v", tt.s, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, n)
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{1000, "1000"},
+		{128 << 20, "128m"},
+		{1536 << 20, "1536m"},
+		{3 << 60, "3e"},
+		{-(64 << 10), "-64k"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatBytes(tt.n))
+			n, err := parseBytes(tt.want, Byte)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.n, n)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Change 一个key的变化, 值均为展开变量后的值
+type Change struct {
+	Key      string
+	OldValue string
+	NewValue string
+}
+
+// ChangeEvent 一次重新加载带来的变化, 加载失败时Err不为nil且配置保持不变
+type ChangeEvent struct {
+	Added   []Change
+	Removed []Change
+	Changed []Change
+	Err     error
+}
+
+// Empty 判断事件中是否没有任何变化
+func (e ChangeEvent) Empty() bool {
+	return len(e.Added) == 0 && len(e.Removed) == 0 && len(e.Changed) == 0 && e.Err == nil
+}
+
+type subscriber struct {
+	id int
+	fn func(ChangeEvent)
+}
+
+// Subscribe 订阅Reload带来的配置变化, 返回取消订阅的函数. 回调在Reload所在的goroutine中同步执行
+func (x *XmlConfig) Subscribe(fn func(ChangeEvent)) (cancel func()) {
+	x.subMu.Lock()
+	defer x.subMu.Unlock()
+	x.nextSubID++
+	id := x.nextSubID
+	x.subscribers = append(x.subscribers, subscriber{id: id, fn: fn})
+	return func() {
+		x.subMu.Lock()
+		defer x.subMu.Unlock()
+		for i, s := range x.subscribers {
+			if s.id == id {
+				x.subscribers = append(x.subscribers[:i:i], x.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notify 通知所有订阅者, 调用时不能持有x.mu
+func (x *XmlConfig) notify(e ChangeEvent) {
+	if e.Empty() {
+		return
+	}
+	x.subMu.Lock()
+	subscribers := x.subscribers
+	x.subMu.Unlock()
+	for _, s := range subscribers {
+		s.fn(e)
+	}
+}
+
+// diffSnapshot 比较两次快照的差异
+func diffSnapshot(old, cur map[string]string) ChangeEvent {
+	var e ChangeEvent
+	for key, value := range cur {
+		if oldValue, ok := old[key]; !ok {
+			e.Added = append(e.Added, Change{Key: key, NewValue: value})
+		} else if oldValue != value {
+			e.Changed = append(e.Changed, Change{Key: key, OldValue: oldValue, NewValue: value})
+		}
+	}
+	for key, value := range old {
+		if _, ok := cur[key]; !ok {
+			e.Removed = append(e.Removed, Change{Key: key, OldValue: value})
+		}
+	}
+	for _, changes := range [][]Change{e.Added, e.Removed, e.Changed} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	}
+	return e
+}
+
+// Watch 每隔interval检查一次资源栈中的文件等WatchableSource,
+// 有来源发生变化时调用Reload重新加载并通知订阅者. 返回停止检查的函数
+func (x *XmlConfig) Watch(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	versions := x.sourceVersions()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cur := x.sourceVersions()
+				changed := false
+				if len(cur) == len(versions) {
+					for i := range cur {
+						changed = changed || cur[i] != versions[i]
+					}
+				}
+				versions = cur
+				if changed {
+					_ = x.Reload()
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
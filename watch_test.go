@@ -0,0 +1,105 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSnapshot(t *testing.T) {
+	e := diffSnapshot(map[string]string{"a": "1", "b": "2", "c": "3"}, map[string]string{"a": "1", "b": "20", "d": "4"})
+	assert.Equal(t, []Change{{Key: "d", NewValue: "4"}}, e.Added)
+	assert.Equal(t, []Change{{Key: "c", OldValue: "3"}}, e.Removed)
+	assert.Equal(t, []Change{{Key: "b", OldValue: "2", NewValue: "20"}}, e.Changed)
+	assert.True(t, diffSnapshot(map[string]string{"a": "1"}, map[string]string{"a": "1"}).Empty())
+}
+
+func TestXmlConfig_Watch(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempXml(t, dir, "core-site.xml", coreSite)
+	x := NewXmlConfig()
+	assert.Nil(t, x.ReadXmlFile(path))
+	events := make(chan ChangeEvent, 10)
+	cancel := x.Subscribe(func(e ChangeEvent) { events <- e })
+	defer cancel()
+	stop := x.Watch(5 * time.Millisecond)
+	defer stop()
+
+	modify := func(content string, n int) {
+		writeTempXml(t, dir, "core-site.xml", content)
+		mtime := time.Now().Add(time.Duration(n) * time.Second)
+		assert.Nil(t, os.Chtimes(filepath.Join(dir, "core-site.xml"), mtime, mtime))
+	}
+	wait := func() ChangeEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change event")
+		}
+		return ChangeEvent{}
+	}
+
+	modify(`<configuration>
+    <property><name>fs.defaultFS</name><value>hdfs://nn2:8020</value></property>
+    <property><name>dfs.replication</name><value>2</value></property>
+</configuration>`, 1)
+	e := wait()
+	assert.Nil(t, e.Err)
+	assert.Equal(t, []Change{{Key: "dfs.replication", NewValue: "2"}}, e.Added)
+	assert.Equal(t, []Change{{Key: "io.file.buffer.size", OldValue: "65536"}}, e.Removed)
+	assert.Equal(t, []Change{{Key: "fs.defaultFS", OldValue: "hdfs://nn:8020", NewValue: "hdfs://nn2:8020"}}, e.Changed)
+	assert.Equal(t, "hdfs://nn2:8020", x.GetString("fs.defaultFS", ""))
+
+	modify("<configuration><property><name>fs.defaultFS", 2)
+	e = wait()
+	assert.NotNil(t, e.Err)
+	assert.Equal(t, "hdfs://nn2:8020", x.GetString("fs.defaultFS", ""))
+	assert.Equal(t, "2", x.GetString("dfs.replication", ""))
+}
+
+func TestXmlConfig_Subscribe(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempXml(t, dir, "core-site.xml", coreSite)
+	x := NewXmlConfig()
+	assert.Nil(t, x.ReadXmlFile(path))
+	var events []ChangeEvent
+	cancel := x.Subscribe(func(e ChangeEvent) { events = append(events, e) })
+	x.SetString("fs.defaultFS", "hdfs://other:8020")
+	assert.Nil(t, x.Reload())
+	assert.Equal(t, 0, len(events))
+
+	writeTempXml(t, dir, "core-site.xml", "<configuration></configuration>")
+	assert.Nil(t, x.Reload())
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, []Change{{Key: "io.file.buffer.size", OldValue: "65536"}}, events[0].Removed)
+
+	cancel()
+	writeTempXml(t, dir, "core-site.xml", coreSite)
+	assert.Nil(t, x.Reload())
+	assert.Equal(t, 1, len(events))
+}
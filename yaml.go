@@ -0,0 +1,202 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BuildYamlData 将配置构建为yaml, 描述作为键前的注释. nested为false时每个key为一个顶层键,
+// 为true时按.拆分为嵌套的映射, 既有值又是其他key前缀的key及其后代在所在层级保持扁平
+func (x *XmlConfig) BuildYamlData(nested bool) ([]byte, error) {
+	root := &yamlTree{}
+	for _, p := range x.Properties() {
+		segments := []string{p.Name}
+		if nested && !strings.Contains("."+p.Name+".", "..") {
+			segments = strings.Split(p.Name, ".")
+		}
+		root.insert(segments, p)
+	}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root.node("")}}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// ParseYamlData 解析yaml配置, 与ParseXmlData一样不加入资源栈. 嵌套的映射以.连接为key, 列表以逗号连接为值,
+// 键前的注释作为描述
+func (x *XmlConfig) ParseYamlData(data []byte) error {
+	props, err := decodeYaml(data)
+	if err != nil {
+		return err
+	}
+	x.applyOnce(props, nil)
+	return nil
+}
+
+// yamlTree 按key的各段组成的树
+type yamlTree struct {
+	order    []string
+	children map[string]*yamlTree
+	prop     *Property
+}
+
+func (t *yamlTree) insert(segments []string, p Property) {
+	if len(segments) == 0 {
+		t.prop = &p
+		return
+	}
+	if t.children == nil {
+		t.children = make(map[string]*yamlTree)
+	}
+	c, ok := t.children[segments[0]]
+	if !ok {
+		c = &yamlTree{}
+		t.children[segments[0]] = c
+		t.order = append(t.order, segments[0])
+	}
+	c.insert(segments[1:], p)
+}
+
+// props 按顺序返回t及其后代中的所有配置项
+func (t *yamlTree) props() []Property {
+	var props []Property
+	if t.prop != nil {
+		props = append(props, *t.prop)
+	}
+	for _, seg := range t.order {
+		props = append(props, t.children[seg].props()...)
+	}
+	return props
+}
+
+// node 生成t对应的映射, prefix为t对应的key前缀
+func (t *yamlTree) node(prefix string) *yaml.Node {
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	for _, seg := range t.order {
+		c := t.children[seg]
+		if c.prop == nil {
+			m.Content = append(m.Content, yamlKey(seg, ""), c.node(prefix+seg+"."))
+			continue
+		}
+		for _, p := range c.props() {
+			m.Content = append(m.Content, yamlKey(strings.TrimPrefix(p.Name, prefix), p.Description), yamlValue(p.RawValue))
+		}
+	}
+	return m
+}
+
+// yamlKey 生成带有描述注释的键
+func yamlKey(key, description string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	if lines := descriptionLines(description); len(lines) > 0 {
+		n.HeadComment = "# " + strings.Join(lines, "\n# ")
+	}
+	return n
+}
+
+// yamlValue 生成字符串值, 数字和布尔等会被加上引号
+func yamlValue(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// decodeYaml 解析yaml, 顶层必须为映射
+func decodeYaml(data []byte) ([]Property, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	props := make([]Property, 0)
+	if len(doc.Content) == 0 {
+		return props, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("yaml root must be a mapping")
+	}
+	return props, flattenYaml(root, "", &props)
+}
+
+// flattenYaml 将映射m展开为配置项
+func flattenYaml(m *yaml.Node, prefix string, props *[]Property) error {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, v := m.Content[i], resolveYamlAlias(m.Content[i+1])
+		key := prefix + k.Value
+		switch v.Kind {
+		case yaml.MappingNode:
+			if err := flattenYaml(v, key+".", props); err != nil {
+				return err
+			}
+			continue
+		case yaml.SequenceNode:
+			items := make([]string, 0, len(v.Content))
+			for _, item := range v.Content {
+				item = resolveYamlAlias(item)
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("yaml line %d: list %s must only contain scalars", item.Line, key)
+				}
+				items = append(items, yamlScalar(item))
+			}
+			*props = append(*props, yamlProperty(key, strings.Join(items, ","), k))
+		default:
+			*props = append(*props, yamlProperty(key, yamlScalar(v), k))
+		}
+	}
+	return nil
+}
+
+func yamlProperty(key, value string, k *yaml.Node) Property {
+	var lines []string
+	for _, line := range strings.Split(k.HeadComment, "\n") {
+		if line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#")); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return Property{Name: key, Value: value, RawValue: value, Description: strings.Join(lines, "\n")}
+}
+
+// yamlScalar 返回标量的值, null为空字符串
+func yamlScalar(n *yaml.Node) string {
+	if n.Tag == "!!null" {
+		return ""
+	}
+	return n.Value
+}
+
+func resolveYamlAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
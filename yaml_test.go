@@ -0,0 +1,107 @@
+// MIT License
+//
+// Copyright (c) 2022 孟琦
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package xmlconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXmlConfig_BuildYamlData(t *testing.T) {
+	x := NewXmlConfig()
+	x.SetStringWithDescription("dfs.replication", "3", "Default block replication.")
+	x.SetString("dfs.namenode", "nn")
+	x.SetString("dfs.namenode.name.dir", "/data/nn")
+	x.SetString("dfs.permissions.enabled", "true")
+	x.SetString("fs.defaultFS", "hdfs://nn:8020")
+
+	tests := []struct {
+		name   string
+		nested bool
+		want   string
+	}{
+		{
+			name: "flat",
+			want: `# Default block replication.
+dfs.replication: "3"
+dfs.namenode: nn
+dfs.namenode.name.dir: /data/nn
+dfs.permissions.enabled: "true"
+fs.defaultFS: hdfs://nn:8020
+`,
+		},
+		{
+			name:   "nested",
+			nested: true,
+			want: `dfs:
+  # Default block replication.
+  replication: "3"
+  namenode: nn
+  namenode.name.dir: /data/nn
+  permissions:
+    enabled: "true"
+fs:
+  defaultFS: hdfs://nn:8020
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := x.BuildYamlData(tt.nested)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(data))
+
+			live := NewXmlConfig()
+			assert.Nil(t, live.ParseYamlData(data))
+			assert.ElementsMatch(t, x.GetConfigKeys(), live.GetConfigKeys())
+			assert.True(t, Diff(x, live).Empty())
+		})
+	}
+}
+
+func TestXmlConfig_ParseYamlData(t *testing.T) {
+	x := NewXmlConfig()
+	assert.Nil(t, x.ParseYamlData([]byte(`
+defaults: &defaults
+  timeout: 30s
+dfs:
+  # replication factor
+  replication: 3
+  hosts: [a, b, c]
+  empty:
+yarn: *defaults
+`)))
+	assert.Equal(t, map[string]string{
+		"defaults.timeout": "30s",
+		"dfs.replication":  "3",
+		"dfs.hosts":        "a,b,c",
+		"dfs.empty":        "",
+		"yarn.timeout":     "30s",
+	}, x.Snapshot())
+	p, ok := x.Lookup("dfs.replication")
+	assert.True(t, ok)
+	assert.Equal(t, "replication factor", p.Description)
+
+	assert.NotNil(t, x.ParseYamlData([]byte("- a\n- b\n")))
+	assert.NotNil(t, x.ParseYamlData([]byte("a: [b\n")))
+}